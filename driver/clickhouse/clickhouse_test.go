@@ -0,0 +1,98 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+
+	chdriver "github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/cotton-go/pkg/ssh"
+)
+
+func TestOptions(t *testing.T) {
+	tests := []struct {
+		conf     Config
+		protocol chdriver.Protocol
+		addr     []string
+	}{
+		{
+			conf:     Config{DSN: "clickhouse://default:pw@ch1:9000,ch2:9001/app", Protocol: ProtocolHTTP},
+			protocol: chdriver.HTTP,
+			addr:     []string{"ch1:8123", "ch2:9001"},
+		},
+		{
+			conf:     Config{DSN: "clickhouse://default:pw@ch1:9440/app?secure=true", Protocol: ProtocolHTTP},
+			protocol: chdriver.HTTP,
+			addr:     []string{"ch1:8443"},
+		},
+		{
+			conf:     Config{DSN: "http://ch1:8123/app", Protocol: ProtocolNative},
+			protocol: chdriver.Native,
+			addr:     []string{"ch1:9000"},
+		},
+		{
+			conf:     Config{DSN: "http://ch1:8123/app", Protocol: ProtocolHTTP},
+			protocol: chdriver.HTTP,
+			addr:     []string{"ch1:8123"},
+		},
+		{
+			conf:     Config{DSN: "clickhouse://ch1:9000/app", SSH: &ssh.Config{Host: "bastion"}},
+			protocol: chdriver.Native,
+			addr:     []string{"ch1:9000"},
+		},
+	}
+
+	for _, tt := range tests {
+		opts, err := tt.conf.options()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.conf.DSN, err)
+		}
+		if opts.Protocol != tt.protocol || !reflect.DeepEqual(opts.Addr, tt.addr) {
+			t.Fatalf("%s: got %v %v, want %v %v", tt.conf.DSN, opts.Protocol, opts.Addr, tt.protocol, tt.addr)
+		}
+	}
+
+	if opts, err := (Config{DSN: "clickhouse://ch1:9000/app"}).options(); err != nil || opts.Protocol != chdriver.Native {
+		t.Fatalf("got %v, %v", opts, err)
+	}
+	if _, err := (Config{Protocol: ProtocolHTTP}).options(); !errors.Is(err, ErrDSNParse) {
+		t.Fatalf("got %v, want ErrDSNParse", err)
+	}
+	if _, err := (Config{DSN: "clickhouse://"}).options(); !errors.Is(err, ErrDSNParse) {
+		t.Fatalf("got %v, want ErrDSNParse", err)
+	}
+}
+
+func TestNewWithErrorSSH(t *testing.T) {
+	// 一个已经关闭的端口，SSH 连接失败时不会创建连接池
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	ln.Close()
+	p, _ := strconv.Atoi(port)
+
+	sshConf := &ssh.Config{Host: "127.0.0.1", Port: p, User: "ops", Password: "secret"}
+	if _, err := NewWithError(Config{DSN: "clickhouse://ch1:9000/app", SSH: sshConf}); !errors.Is(err, ErrSSHDial) {
+		t.Fatalf("got %v, want ErrSSHDial", err)
+	}
+	if _, ok := tunnels.Client(sshConf.Key()); ok {
+		t.Fatal("failed tunnel must not be registered")
+	}
+
+	// 同时设置 Conn 与 SSH 时返回错误，不会覆盖调用方的连接池
+	conn := chdriver.OpenDB(&chdriver.Options{Addr: []string{"ch1:9000"}})
+	defer conn.Close()
+	if _, err := NewWithError(Config{DSN: "clickhouse://ch1:9000/app", Conn: conn, SSH: sshConf}); !errors.Is(err, ErrDSNParse) {
+		t.Fatalf("got %v, want ErrDSNParse", err)
+	}
+
+	// 拨号函数通过共享的注册表查找 SSH 连接，连接已释放时返回 ErrTunnelClosed
+	if _, err := tunnelDialer(sshConf.Key())(context.Background(), "ch1:9000"); !errors.Is(err, ssh.ErrTunnelClosed) {
+		t.Fatalf("got %v, want ErrTunnelClosed", err)
+	}
+}
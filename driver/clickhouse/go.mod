@@ -0,0 +1,38 @@
+module github.com/cotton-go/pkg/driver/clickhouse
+
+go 1.21
+
+require (
+	github.com/ClickHouse/clickhouse-go/v2 v2.23.2
+	github.com/cotton-go/pkg/driver v0.0.0
+	github.com/cotton-go/pkg/ssh v0.0.0-20240816034421-034ecfc24f77
+	gorm.io/driver/clickhouse v0.6.1
+	gorm.io/gorm v1.25.11
+)
+
+require (
+	github.com/ClickHouse/ch-go v0.61.5 // indirect
+	github.com/andybalholm/brotli v1.1.0 // indirect
+	github.com/go-faster/city v1.0.1 // indirect
+	github.com/go-faster/errors v0.7.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/hashicorp/go-version v1.6.0 // indirect
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	github.com/klauspost/compress v1.17.8 // indirect
+	github.com/paulmach/orb v0.11.1 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/segmentio/asm v1.2.0 // indirect
+	github.com/shopspring/decimal v1.4.0 // indirect
+	go.opentelemetry.io/otel v1.26.0 // indirect
+	go.opentelemetry.io/otel/trace v1.26.0 // indirect
+	golang.org/x/crypto v0.26.0 // indirect
+	golang.org/x/sys v0.23.0 // indirect
+	golang.org/x/text v0.17.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/cotton-go/pkg/ssh => ../../ssh
+
+replace github.com/cotton-go/pkg/driver => ../
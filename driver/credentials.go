@@ -0,0 +1,143 @@
+package driver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCommandTTL 是 CommandProvider 缓存命令输出的默认时长。
+const DefaultCommandTTL = time.Minute
+
+// Credentials 定义数据库的登录凭据。
+type Credentials struct {
+	User     string // 用户名，为空时使用 DSN 中的用户名
+	Password string // 密码
+}
+
+// CredentialProvider 在每次建立新的物理连接时提供数据库的登录凭据。
+// 数据库密码定期轮换时，连接池中新建立的连接会使用新的密码，无需重启程序。
+// 实现需要可以安全地并发调用。
+type CredentialProvider interface {
+	// Credentials 返回当前的登录凭据，ctx 为建立连接时的上下文。
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// CredentialProviderFunc 是一个函数形式的 CredentialProvider。
+type CredentialProviderFunc func(ctx context.Context) (Credentials, error)
+
+// Credentials 实现 CredentialProvider 接口。
+func (f CredentialProviderFunc) Credentials(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// FileProvider 从文件中读取登录凭据，文件的修改时间或大小变化后重新读取。
+// 适用于 Kubernetes Secret、Vault Agent 等以文件形式下发并定期更新的密码。
+type FileProvider struct {
+	PasswordFile string // 密码文件路径，文件内容为密码，首尾的空白字符会被去掉
+	UserFile     string // 用户名文件路径，为空时使用 DSN 中的用户名
+
+	mu    sync.Mutex
+	cache map[string]fileCache
+}
+
+// fileCache 记录文件的内容及读取时的状态。
+type fileCache struct {
+	modTime time.Time
+	size    int64
+	content string
+}
+
+// Credentials 实现 CredentialProvider 接口。
+func (p *FileProvider) Credentials(context.Context) (Credentials, error) {
+	password, err := p.read(p.PasswordFile)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	var user string
+	if p.UserFile != "" {
+		if user, err = p.read(p.UserFile); err != nil {
+			return Credentials{}, err
+		}
+	}
+
+	return Credentials{User: user, Password: password}, nil
+}
+
+// read 读取文件内容，文件未变化时返回缓存的内容。
+func (p *FileProvider) read(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to stat credential file: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cached, ok := p.cache[path]; ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.content, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read credential file: %w", err)
+	}
+
+	if p.cache == nil {
+		p.cache = make(map[string]fileCache)
+	}
+	content := strings.TrimSpace(string(data))
+	p.cache[path] = fileCache{modTime: info.ModTime(), size: info.Size(), content: content}
+
+	return content, nil
+}
+
+// CommandProvider 执行外部命令获取密码，命令的标准输出即为密码，首尾的空白字符会被去掉。
+// 适用于 aws rds generate-db-auth-token 等按需生成临时密码的命令。
+type CommandProvider struct {
+	Command []string      // 命令及其参数，例如 []string{"vault", "read", "-field=password", "database/creds/app"}
+	User    string        // 用户名，为空时使用 DSN 中的用户名
+	TTL     time.Duration // 命令输出的缓存时长，默认为 DefaultCommandTTL，小于 0 时每次都执行命令
+
+	mu        sync.Mutex
+	password  string
+	fetchedAt time.Time
+}
+
+// Credentials 实现 CredentialProvider 接口。
+func (p *CommandProvider) Credentials(ctx context.Context) (Credentials, error) {
+	if len(p.Command) == 0 {
+		return Credentials{}, fmt.Errorf("credential command is empty")
+	}
+
+	ttl := p.TTL
+	if ttl == 0 {
+		ttl = DefaultCommandTTL
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if ttl > 0 && !p.fetchedAt.IsZero() && time.Since(p.fetchedAt) < ttl {
+		return Credentials{User: p.User, Password: p.password}, nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Command[0], p.Command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return Credentials{}, fmt.Errorf("credential command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	p.password = strings.TrimSpace(stdout.String())
+	p.fetchedAt = time.Now()
+
+	return Credentials{User: p.User, Password: p.password}, nil
+}
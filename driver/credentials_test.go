@@ -0,0 +1,72 @@
+package driver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "password")
+	userFile := filepath.Join(dir, "user")
+	if err := os.WriteFile(passwordFile, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(userFile, []byte("app"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &FileProvider{PasswordFile: passwordFile, UserFile: userFile}
+	creds, err := provider.Credentials(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.User != "app" || creds.Password != "first" {
+		t.Fatalf("unexpected credentials %+v", creds)
+	}
+
+	// 密码轮换后读取新的内容
+	if err := os.WriteFile(passwordFile, []byte("rotated\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if creds, err = provider.Credentials(context.Background()); err != nil || creds.Password != "rotated" {
+		t.Fatalf("got %+v, %v, want rotated password", creds, err)
+	}
+
+	if _, err := (&FileProvider{PasswordFile: filepath.Join(dir, "missing")}).Credentials(context.Background()); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+}
+
+func TestCommandProvider(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "counter")
+	provider := &CommandProvider{
+		Command: []string{"sh", "-c", "echo x >> " + counter + "; echo secret"},
+		User:    "app",
+	}
+
+	for i := 0; i < 2; i++ {
+		creds, err := provider.Credentials(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if creds.User != "app" || creds.Password != "secret" {
+			t.Fatalf("unexpected credentials %+v", creds)
+		}
+	}
+
+	// 缓存时长内只执行一次命令
+	data, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "x\n" {
+		t.Fatalf("command ran %d times, want 1", len(data)/2)
+	}
+
+	if _, err := (&CommandProvider{Command: []string{"sh", "-c", "exit 1"}}).Credentials(context.Background()); err == nil {
+		t.Fatal("expected error for failed command")
+	}
+}
@@ -0,0 +1,99 @@
+package driver
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Config 是各数据库驱动配置的公共接口，例如 mysql.Config 与 postgres.Config。
+// 通过 ParseURL 得到的配置同时实现了 fmt.Stringer，返回隐藏了敏感信息的连接 URL。
+type Config interface {
+	// Dialector 根据配置创建 Gorm 数据库连接器。
+	Dialector() (gorm.Dialector, error)
+}
+
+// Opener 是可以打开并关闭数据库的配置，mysql.Config 与 postgres.Config 实现了该接口。
+// 与 Dialector 不同，Open 同时设置连接池、重试与插件，Close 负责释放 Open 占用的 SSH 隧道。
+type Opener interface {
+	// Open 根据配置打开数据库。
+	Open(opts ...gorm.Option) (*gorm.DB, error)
+	// Close 关闭 Open 打开的数据库，并释放其占用的 SSH 隧道。
+	Close(db *gorm.DB) error
+}
+
+// URLParser 将连接 URL 解析为驱动的配置。
+type URLParser func(rawURL string) (Config, error)
+
+var (
+	mu      sync.RWMutex
+	parsers = make(map[string]URLParser)
+	configs = make(map[string]func() Config)
+)
+
+// RegisterURL 注册一个 URL scheme 对应的解析函数，通常在驱动包的 init 函数中调用。
+// 带有 "+ssh" 后缀的 scheme 会使用同一个解析函数，无需单独注册。
+// 重复注册同一个 scheme 或 parser 为 nil 时会 panic。
+//
+// 参数:
+//   - scheme: URL 的 scheme，例如 "mysql"。
+//   - parser: 解析函数。
+func RegisterURL(scheme string, parser URLParser) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if parser == nil {
+		panic("driver: RegisterURL parser is nil")
+	}
+	if _, dup := parsers[scheme]; dup {
+		panic("driver: RegisterURL called twice for scheme " + scheme)
+	}
+
+	parsers[scheme] = parser
+}
+
+// Schemes 返回所有已注册的 URL scheme，按字母顺序排列。
+func Schemes() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	schemes := make([]string, 0, len(parsers))
+	for scheme := range parsers {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+
+	return schemes
+}
+
+// ParseURL 根据 URL 的 scheme 找到已注册的驱动并解析连接 URL。
+// 需要先导入对应的驱动包，例如 import _ "github.com/cotton-go/pkg/driver/mysql"。
+//
+// 参数:
+//   - rawURL: 连接 URL，例如 mysql+ssh://dbuser:pw@db:3306/app?ssh_host=bastion&ssh_user=ops&ssh_key=/keys/id。
+//
+// 返回值:
+//   - Config: 驱动的配置，可以通过类型断言转换为具体驱动的 Config。
+//   - error: 如果 URL 格式错误或 scheme 未注册，则返回错误信息。
+func ParseURL(rawURL string) (Config, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse connection url: %w", err)
+	}
+
+	scheme := strings.TrimSuffix(u.Scheme, "+ssh")
+
+	mu.RLock()
+	parser, ok := parsers[scheme]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown connection url scheme %q (forgotten import?)", u.Scheme)
+	}
+
+	return parser(rawURL)
+}
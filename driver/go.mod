@@ -0,0 +1,14 @@
+module github.com/cotton-go/pkg/driver
+
+go 1.21
+
+require (
+	gopkg.in/yaml.v3 v3.0.1
+	gorm.io/gorm v1.25.11
+)
+
+require (
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
@@ -0,0 +1,181 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultDrainTimeout 是 Handle.Close 等待正在执行的查询结束的默认时长。
+const DefaultDrainTimeout = 30 * time.Second
+
+// drainPollInterval 是检查连接池中的连接是否全部归还的间隔。
+const drainPollInterval = 50 * time.Millisecond
+
+// Handle 持有打开的数据库及其占用的 SSH 隧道。
+// 只关闭 sql.DB 不会释放 SSH 连接，通过 Handle 关闭时先等待主库与只读副本连接池中的查询结束，再释放 SSH 连接，
+// 同一跳板机上的其他数据库仍在使用时 SSH 连接保持打开。
+type Handle struct {
+	db     *gorm.DB
+	opener Opener
+
+	once sync.Once
+	err  error
+}
+
+// OpenHandle 按配置打开数据库，并返回持有数据库与 SSH 隧道的 Handle。
+//
+// 参数:
+//   - opener: 数据库配置，例如 mysql.Config 或 postgres.Config。
+//   - opts: 传递给 gorm.Open 的选项。
+//
+// 返回值:
+//   - *Handle: 持有数据库的 Handle，不再使用时需要调用 Close。
+//   - error: 如果打开数据库失败，则返回错误信息。
+func OpenHandle(opener Opener, opts ...gorm.Option) (*Handle, error) {
+	db, err := opener.Open(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Handle{db: db, opener: opener}, nil
+}
+
+// DB 返回打开的数据库。
+func (h *Handle) DB() *gorm.DB {
+	return h.db
+}
+
+// Close 关闭数据库，最多等待 DefaultDrainTimeout，等同于 Shutdown。
+func (h *Handle) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultDrainTimeout)
+	defer cancel()
+
+	return h.Shutdown(ctx)
+}
+
+// Shutdown 关闭数据库：连接池不再接受新的查询，等待正在执行的查询结束后释放 SSH 隧道。
+// 重复调用时只关闭一次，并返回第一次关闭的结果。
+//
+// 参数:
+//   - ctx: 上下文，取消后不再等待查询结束，直接释放 SSH 隧道。
+//
+// 返回值:
+//   - error: 如果等待超时或关闭失败，则返回错误信息。
+func (h *Handle) Shutdown(ctx context.Context) error {
+	h.once.Do(func() {
+		drainErr := h.drain(ctx)
+		if err := h.opener.Close(h.db); err != nil {
+			h.err = err
+			return
+		}
+		h.err = drainErr
+	})
+
+	return h.err
+}
+
+// ShutdownHook 返回关闭数据库的函数，可以直接作为 signal.Shutdown 的钩子，关闭失败时记录日志。
+//
+//	signal.Shutdown(ctx, handle.ShutdownHook(10*time.Second))
+//
+// 参数:
+//   - timeout: 等待正在执行的查询结束的时长，小于等于 0 时使用 DefaultDrainTimeout。
+//
+// 返回值:
+//   - func(): 关闭数据库的函数。
+func (h *Handle) ShutdownHook(timeout time.Duration) func() {
+	if timeout <= 0 {
+		timeout = DefaultDrainTimeout
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		if err := h.Shutdown(ctx); err != nil {
+			slog.Error("database shutdown failed", slog.Any("error", err))
+		}
+	}
+}
+
+// drain 关闭主库与只读副本的连接池，并等待所有连接归还。
+// sql.DB.Close 只关闭空闲连接，使用中的连接在查询结束归还时才关闭，因此需要等待打开的连接数降为 0。
+func (h *Handle) drain(ctx context.Context) error {
+	pools, err := ConnPools(h.db)
+	if err != nil {
+		return err
+	}
+	for _, pool := range pools {
+		if err := pool.Close(); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		open := 0
+		for _, pool := range pools {
+			open += pool.Stats().OpenConnections
+		}
+		if open == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database drain interrupted with %d connections in use: %w", open, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// ConnPools 返回数据库使用的所有连接池，第一个为主库，其后为读写分离插件持有的只读副本，同一连接池只返回一次。
+// 只读副本的连接池通过插件的 Call 方法获取，例如 dbresolver.DBResolver。
+//
+// 参数:
+//   - db: 打开的数据库。
+//
+// 返回值:
+//   - []*sql.DB: 数据库使用的连接池。
+//   - error: 如果无法获取主库的连接池，则返回错误信息。
+func ConnPools(db *gorm.DB) ([]*sql.DB, error) {
+	primary, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	pools := []*sql.DB{primary}
+	seen := map[*sql.DB]bool{primary: true}
+	for _, plugin := range db.Config.Plugins {
+		caller, ok := plugin.(interface {
+			Call(func(gorm.ConnPool) error) error
+		})
+		if !ok {
+			continue
+		}
+
+		_ = caller.Call(func(connPool gorm.ConnPool) error {
+			sqlDB, ok := connPool.(*sql.DB)
+			if !ok {
+				if connector, isConnector := connPool.(gorm.GetDBConnector); isConnector {
+					sqlDB, _ = connector.GetDBConn()
+				}
+			}
+			if sqlDB != nil && !seen[sqlDB] {
+				seen[sqlDB] = true
+				pools = append(pools, sqlDB)
+			}
+			return nil
+		})
+	}
+
+	return pools, nil
+}
@@ -0,0 +1,220 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// nopConn 是一个不执行任何操作的数据库连接。
+type nopConn struct{}
+
+func (nopConn) Prepare(string) (sqldriver.Stmt, error) { return nil, errors.New("not implemented") }
+func (nopConn) Close() error                           { return nil }
+func (nopConn) Begin() (sqldriver.Tx, error)           { return nil, errors.New("not implemented") }
+
+// nopConnector 总是返回 nopConn 的连接器。
+type nopConnector struct{}
+
+func (nopConnector) Connect(context.Context) (sqldriver.Conn, error) { return nopConn{}, nil }
+func (nopConnector) Driver() sqldriver.Driver                        { return nil }
+
+// poolDialector 是使用给定连接池的 dryRunDialector，用于需要 db.DB() 的测试。
+type poolDialector struct {
+	dryRunDialector
+	conn *sql.DB
+}
+
+func (d poolDialector) Initialize(db *gorm.DB) error {
+	db.ConnPool = d.conn
+	return d.dryRunDialector.Initialize(db)
+}
+
+// replicaPlugin 模拟读写分离插件，通过 Call 暴露主库与只读副本的连接池。
+type replicaPlugin struct {
+	db       *gorm.DB
+	replicas []*sql.DB
+}
+
+func (p *replicaPlugin) Name() string { return "replica" }
+
+func (p *replicaPlugin) Initialize(db *gorm.DB) error {
+	p.db = db
+	return nil
+}
+
+func (p *replicaPlugin) Call(fc func(gorm.ConnPool) error) error {
+	pools := []gorm.ConnPool{p.db.ConnPool}
+	for _, replica := range p.replicas {
+		pools = append(pools, replica)
+	}
+	for _, pool := range pools {
+		if err := fc(pool); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// poolOpener 使用给定连接池打开数据库，并记录 Close 时主库与只读副本中打开的连接数。
+type poolOpener struct {
+	sqlDB       *sql.DB
+	replicas    []*sql.DB
+	closes      *atomic.Int32
+	openAtClose *atomic.Int32
+}
+
+func newPoolOpener(replicas int) poolOpener {
+	o := poolOpener{sqlDB: sql.OpenDB(nopConnector{}), closes: new(atomic.Int32), openAtClose: new(atomic.Int32)}
+	for i := 0; i < replicas; i++ {
+		o.replicas = append(o.replicas, sql.OpenDB(nopConnector{}))
+	}
+
+	return o
+}
+
+func (o poolOpener) Open(opts ...gorm.Option) (*gorm.DB, error) {
+	db, err := gorm.Open(poolDialector{conn: o.sqlDB}, append(opts, &gorm.Config{Logger: logger.Discard})...)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.replicas) > 0 {
+		if err := db.Use(&replicaPlugin{replicas: o.replicas}); err != nil {
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
+
+func (o poolOpener) Close(db *gorm.DB) error {
+	o.closes.Add(1)
+
+	open := o.sqlDB.Stats().OpenConnections
+	for _, replica := range o.replicas {
+		open += replica.Stats().OpenConnections
+	}
+	o.openAtClose.Store(int32(open))
+	return nil
+}
+
+func TestHandle(t *testing.T) {
+	ctx := context.Background()
+	opener := newPoolOpener(0)
+
+	h, err := OpenHandle(opener)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if h.DB() == nil {
+		t.Fatal("handle has no db")
+	}
+
+	// 模拟一个正在执行的查询
+	conn, err := opener.sqlDB.Conn(ctx)
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- h.Close() }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("close returned before the pool drained: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if opener.closes.Load() != 0 {
+		t.Fatal("tunnel released before the pool drained")
+	}
+
+	conn.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if opener.closes.Load() != 1 || opener.openAtClose.Load() != 0 {
+		t.Fatalf("closes = %d, open at close = %d, want 1, 0", opener.closes.Load(), opener.openAtClose.Load())
+	}
+
+	// 重复关闭不会再次释放隧道
+	h.ShutdownHook(time.Second)()
+	if opener.closes.Load() != 1 {
+		t.Fatalf("closes = %d after second close, want 1", opener.closes.Load())
+	}
+}
+
+func TestHandleDrainTimeout(t *testing.T) {
+	opener := newPoolOpener(0)
+	h, err := OpenHandle(opener)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	conn, err := opener.sqlDB.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// 超时后仍然释放隧道，并返回超时错误
+	if err := h.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("shutdown error = %v, want context.DeadlineExceeded", err)
+	}
+	if opener.closes.Load() != 1 {
+		t.Fatalf("closes = %d, want 1", opener.closes.Load())
+	}
+}
+
+func TestHandleDrainsReplicas(t *testing.T) {
+	opener := newPoolOpener(2)
+	h, err := OpenHandle(opener)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	pools, err := ConnPools(h.DB())
+	if err != nil || len(pools) != 3 || pools[0] != opener.sqlDB {
+		t.Fatalf("pools = %v, %v, want primary and 2 replicas", pools, err)
+	}
+
+	// 模拟只读副本上正在执行的查询
+	conn, err := opener.replicas[1].Conn(context.Background())
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- h.Close() }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("close returned before the replica drained: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	conn.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if opener.closes.Load() != 1 || opener.openAtClose.Load() != 0 {
+		t.Fatalf("closes = %d, open at close = %d, want 1, 0", opener.closes.Load(), opener.openAtClose.Load())
+	}
+
+	// 只读副本的连接池已关闭
+	for _, replica := range opener.replicas {
+		if err := replica.Ping(); err == nil {
+			t.Fatal("replica pool still open")
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+)
+
+// DefaultSlowThreshold 是慢查询的默认阈值。
+const DefaultSlowThreshold = 200 * time.Millisecond
+
+// LogConfig 定义 Gorm 日志的配置选项。
+type LogConfig struct {
+	Level                gormlogger.LogLevel `json:"level,omitempty"`                // 日志级别，默认为 gormlogger.Warn，即只记录错误与慢查询。
+	SlowThreshold        time.Duration       `json:"slowThreshold,omitempty"`        // 慢查询的阈值，默认为 DefaultSlowThreshold，小于 0 时不记录慢查询。
+	Redact               bool                `json:"redact,omitempty"`               // 不在 SQL 中写入参数的值，参数以占位符的形式输出，默认为 false。
+	SampleEvery          int                 `json:"sampleEvery,omitempty"`          // 普通查询每 SampleEvery 条记录一条，错误与慢查询不受影响，默认为 0，即全部记录。
+	IgnoreRecordNotFound bool                `json:"ignoreRecordNotFound,omitempty"` // 不记录 gorm.ErrRecordNotFound 错误，默认为 false。
+}
+
+// Logger 是基于 slog 的 Gorm 日志记录器，实现了 gormlogger.Interface。
+// 每条 SQL 以结构化字段 sql、rows、duration、caller 与 error 输出，可以直接接入现有的日志收集系统。
+type Logger struct {
+	logger *slog.Logger
+	conf   LogConfig
+	count  *atomic.Uint64 // 普通查询的计数，LogMode 返回的副本共享同一个计数
+}
+
+// NewLogger 创建一个基于 slog 的 Gorm 日志记录器。
+//
+// 参数:
+//   - logger: 日志记录器，为 nil 时使用 slog.Default()。
+//   - conf: 日志的配置选项。
+//
+// 返回值:
+//   - *Logger: 可以设置到 gorm.Config.Logger 的日志记录器。
+func NewLogger(logger *slog.Logger, conf LogConfig) *Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if conf.Level == 0 {
+		conf.Level = gormlogger.Warn
+	}
+	if conf.SlowThreshold == 0 {
+		conf.SlowThreshold = DefaultSlowThreshold
+	}
+
+	return &Logger{logger: logger, conf: conf, count: new(atomic.Uint64)}
+}
+
+// LogMode 实现 gormlogger.Interface 接口，返回使用指定日志级别的副本。
+func (l *Logger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	c := *l
+	c.conf.Level = level
+	return &c
+}
+
+// Info 实现 gormlogger.Interface 接口。
+func (l *Logger) Info(ctx context.Context, msg string, data ...any) {
+	if l.conf.Level >= gormlogger.Info {
+		l.log(ctx, slog.LevelInfo, fmt.Sprintf(msg, data...), slog.String("caller", utils.FileWithLineNum()))
+	}
+}
+
+// Warn 实现 gormlogger.Interface 接口。
+func (l *Logger) Warn(ctx context.Context, msg string, data ...any) {
+	if l.conf.Level >= gormlogger.Warn {
+		l.log(ctx, slog.LevelWarn, fmt.Sprintf(msg, data...), slog.String("caller", utils.FileWithLineNum()))
+	}
+}
+
+// Error 实现 gormlogger.Interface 接口。
+func (l *Logger) Error(ctx context.Context, msg string, data ...any) {
+	if l.conf.Level >= gormlogger.Error {
+		l.log(ctx, slog.LevelError, fmt.Sprintf(msg, data...), slog.String("caller", utils.FileWithLineNum()))
+	}
+}
+
+// Trace 实现 gormlogger.Interface 接口，记录一条 SQL 的执行情况。
+// 执行失败的 SQL 以 Error 级别记录，慢查询以 Warn 级别记录，日志级别为 gormlogger.Info 时普通查询以 Info 级别按采样记录。
+func (l *Logger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.conf.Level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	var (
+		level slog.Level
+		msg   string
+	)
+	switch {
+	case err != nil && l.conf.Level >= gormlogger.Error && !(l.conf.IgnoreRecordNotFound && errors.Is(err, gormlogger.ErrRecordNotFound)):
+		level, msg = slog.LevelError, "gorm query failed"
+	case l.conf.SlowThreshold > 0 && elapsed > l.conf.SlowThreshold && l.conf.Level >= gormlogger.Warn:
+		level, msg = slog.LevelWarn, "gorm slow query"
+	case l.conf.Level >= gormlogger.Info:
+		if n := l.conf.SampleEvery; n > 1 && (l.count.Add(1)-1)%uint64(n) != 0 {
+			return
+		}
+		level, msg = slog.LevelInfo, "gorm query"
+	default:
+		return
+	}
+
+	// 日志级别被 handler 过滤时不生成 SQL
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Duration("duration", elapsed),
+		slog.String("caller", utils.FileWithLineNum()),
+	}
+	if level == slog.LevelWarn {
+		attrs = append(attrs, slog.Duration("threshold", l.conf.SlowThreshold))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+
+	l.log(ctx, level, msg, attrs...)
+}
+
+// ParamsFilter 实现 gorm.ParamsFilter 接口，开启 Redact 时去掉参数，SQL 中的参数以占位符的形式输出。
+func (l *Logger) ParamsFilter(_ context.Context, sql string, params ...any) (string, []any) {
+	if l.conf.Redact {
+		return sql, nil
+	}
+
+	return sql, params
+}
+
+// log 输出一条日志。
+func (l *Logger) log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	l.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+var (
+	_ gormlogger.Interface = (*Logger)(nil)
+	_ gorm.ParamsFilter    = (*Logger)(nil)
+)
@@ -0,0 +1,114 @@
+package driver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// decodeLines 解析 JSON 格式的日志。
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("unmarshal %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	buf.Reset()
+
+	return records
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := context.Background()
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	query := func() (string, int64) { return "SELECT * FROM users WHERE id = 1", 1 }
+
+	l := NewLogger(slog.New(handler), LogConfig{SlowThreshold: 50 * time.Millisecond})
+
+	// 默认的 Warn 级别不记录普通查询
+	l.Trace(ctx, time.Now(), query, nil)
+	if records := decodeLines(t, &buf); len(records) != 0 {
+		t.Fatalf("records = %v, want none", records)
+	}
+
+	l.Trace(ctx, time.Now().Add(-time.Second), query, nil)
+	records := decodeLines(t, &buf)
+	if len(records) != 1 || records[0]["msg"] != "gorm slow query" || records[0]["level"] != "WARN" {
+		t.Fatalf("slow records = %v", records)
+	}
+	for _, key := range []string{"sql", "rows", "duration", "caller", "threshold"} {
+		if _, ok := records[0][key]; !ok {
+			t.Fatalf("slow record has no %q: %v", key, records[0])
+		}
+	}
+
+	l.Trace(ctx, time.Now(), query, errors.New("boom"))
+	records = decodeLines(t, &buf)
+	if len(records) != 1 || records[0]["level"] != "ERROR" || records[0]["error"] != "boom" {
+		t.Fatalf("error records = %v", records)
+	}
+
+	l.Trace(ctx, time.Now(), query, gormlogger.ErrRecordNotFound)
+	if records := decodeLines(t, &buf); len(records) != 1 {
+		t.Fatalf("not found records = %v, want 1", records)
+	}
+	ignore := NewLogger(slog.New(handler), LogConfig{IgnoreRecordNotFound: true})
+	ignore.Trace(ctx, time.Now(), query, gormlogger.ErrRecordNotFound)
+	if records := decodeLines(t, &buf); len(records) != 0 {
+		t.Fatalf("ignored not found records = %v, want none", records)
+	}
+
+	l.LogMode(gormlogger.Silent).Trace(ctx, time.Now(), query, errors.New("boom"))
+	if records := decodeLines(t, &buf); len(records) != 0 {
+		t.Fatalf("silent records = %v, want none", records)
+	}
+}
+
+func TestLoggerSampling(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := context.Background()
+	query := func() (string, int64) { return "SELECT 1", 1 }
+
+	l := NewLogger(slog.New(slog.NewJSONHandler(&buf, nil)), LogConfig{Level: gormlogger.Info, SampleEvery: 3})
+	for i := 0; i < 7; i++ {
+		l.Trace(ctx, time.Now(), query, nil)
+	}
+	// 错误不参与采样
+	l.Trace(ctx, time.Now(), query, errors.New("boom"))
+
+	records := decodeLines(t, &buf)
+	if len(records) != 4 {
+		t.Fatalf("records = %d, want 4", len(records))
+	}
+	if records[0]["msg"] != "gorm query" || records[3]["msg"] != "gorm query failed" {
+		t.Fatalf("records = %v", records)
+	}
+}
+
+func TestLoggerRedact(t *testing.T) {
+	l := NewLogger(nil, LogConfig{Redact: true})
+	if sql, params := l.ParamsFilter(context.Background(), "SELECT ?", "secret"); sql != "SELECT ?" || params != nil {
+		t.Fatalf("ParamsFilter() = %q, %v, want params removed", sql, params)
+	}
+
+	l = NewLogger(nil, LogConfig{})
+	if _, params := l.ParamsFilter(context.Background(), "SELECT ?", "secret"); len(params) != 1 {
+		t.Fatalf("ParamsFilter() params = %v, want kept", params)
+	}
+}
@@ -0,0 +1,287 @@
+package driver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultBuckets 是延迟直方图默认的桶上界，单位为秒。
+var DefaultBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// metricsStartKey 是记录操作开始时间的实例设置键。
+const metricsStartKey = "cotton:metrics:start"
+
+// MetricsConfig 定义查询指标的配置选项。
+type MetricsConfig struct {
+	Namespace   string            `json:"namespace,omitempty"`   // Prometheus 指标名称的前缀，默认为 "gorm"。
+	Buckets     []float64         `json:"buckets,omitempty"`     // 延迟直方图的桶上界，单位为秒，默认为 DefaultBuckets。
+	ConstLabels map[string]string `json:"constLabels,omitempty"` // 附加到每个 Prometheus 指标上的标签，例如 {"db": "orders"}，默认为空。
+}
+
+// Metrics 是统计查询指标的 Gorm 插件，按表名与操作类型记录延迟直方图与错误次数。
+// 操作类型为 create、query、update、delete、raw 与 row，没有表名的原生 SQL 的表名为 "unknown"。
+// 指标保存在进程内，可以通过 expvar.Publish 发布，也可以作为 http.Handler 以 Prometheus 文本格式输出。
+// 同一个 Metrics 可以注册到多个 gorm.DB 上，指标会合并统计。
+type Metrics struct {
+	conf MetricsConfig
+
+	mu     sync.RWMutex
+	series map[metricsKey]*metricsSeries
+}
+
+// metricsKey 是一组指标的标签。
+type metricsKey struct {
+	table     string
+	operation string
+}
+
+// metricsSeries 是一组标签对应的指标。
+type metricsSeries struct {
+	mu      sync.Mutex
+	buckets []uint64 // 每个桶的计数，不累加
+	count   uint64
+	sum     float64
+	errors  uint64
+}
+
+// MetricsSnapshot 是一组标签对应的指标快照。
+type MetricsSnapshot struct {
+	Table     string          `json:"table"`     // 表名
+	Operation string          `json:"operation"` // 操作类型
+	Count     uint64          `json:"count"`     // 操作次数，包括失败的操作
+	Errors    uint64          `json:"errors"`    // 失败的次数，不包括 gorm.ErrRecordNotFound
+	Sum       float64         `json:"sum"`       // 总耗时，单位为秒
+	Buckets   []MetricsBucket `json:"buckets"`   // 累计的直方图桶
+}
+
+// MetricsBucket 是直方图的一个桶。
+type MetricsBucket struct {
+	UpperBound float64 `json:"le"`    // 桶的上界，单位为秒
+	Count      uint64  `json:"count"` // 耗时小于等于上界的操作次数
+}
+
+// NewMetrics 创建一个查询指标插件，通过 db.Use 注册。
+//
+// 参数:
+//   - conf: 指标的配置选项。
+//
+// 返回值:
+//   - *Metrics: 查询指标插件。
+func NewMetrics(conf MetricsConfig) *Metrics {
+	if conf.Namespace == "" {
+		conf.Namespace = "gorm"
+	}
+	if len(conf.Buckets) == 0 {
+		conf.Buckets = DefaultBuckets
+	}
+	conf.Buckets = append([]float64(nil), conf.Buckets...)
+	sort.Float64s(conf.Buckets)
+
+	return &Metrics{conf: conf, series: make(map[metricsKey]*metricsSeries)}
+}
+
+// Name 实现 gorm.Plugin 接口。
+func (m *Metrics) Name() string {
+	return "cotton:metrics"
+}
+
+// Initialize 实现 gorm.Plugin 接口，在每种操作的所有回调之前与之后注册回调。
+func (m *Metrics) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("*").Register("cotton:metrics:before_create", m.before),
+		callbacks.Create().After("*").Register("cotton:metrics:after_create", m.after("create")),
+		callbacks.Query().Before("*").Register("cotton:metrics:before_query", m.before),
+		callbacks.Query().After("*").Register("cotton:metrics:after_query", m.after("query")),
+		callbacks.Update().Before("*").Register("cotton:metrics:before_update", m.before),
+		callbacks.Update().After("*").Register("cotton:metrics:after_update", m.after("update")),
+		callbacks.Delete().Before("*").Register("cotton:metrics:before_delete", m.before),
+		callbacks.Delete().After("*").Register("cotton:metrics:after_delete", m.after("delete")),
+		callbacks.Raw().Before("*").Register("cotton:metrics:before_raw", m.before),
+		callbacks.Raw().After("*").Register("cotton:metrics:after_raw", m.after("raw")),
+		callbacks.Row().Before("*").Register("cotton:metrics:before_row", m.before),
+		callbacks.Row().After("*").Register("cotton:metrics:after_row", m.after("row")),
+	)
+}
+
+// before 记录操作的开始时间。
+func (m *Metrics) before(db *gorm.DB) {
+	db.InstanceSet(metricsStartKey, time.Now())
+}
+
+// after 返回记录操作耗时与错误的回调。
+func (m *Metrics) after(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(metricsStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		failed := db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound)
+
+		m.observe(metricsKey{table: table, operation: operation}, time.Since(start), failed)
+	}
+}
+
+// observe 记录一次操作。
+func (m *Metrics) observe(key metricsKey, elapsed time.Duration, failed bool) {
+	m.mu.RLock()
+	s, ok := m.series[key]
+	m.mu.RUnlock()
+
+	if !ok {
+		m.mu.Lock()
+		if s, ok = m.series[key]; !ok {
+			s = &metricsSeries{buckets: make([]uint64, len(m.conf.Buckets))}
+			m.series[key] = s
+		}
+		m.mu.Unlock()
+	}
+
+	seconds := elapsed.Seconds()
+	i := sort.SearchFloat64s(m.conf.Buckets, seconds)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i < len(s.buckets) {
+		s.buckets[i]++
+	}
+	s.count++
+	s.sum += seconds
+	if failed {
+		s.errors++
+	}
+}
+
+// Snapshot 返回当前所有指标的快照，按表名与操作类型排序。
+func (m *Metrics) Snapshot() []MetricsSnapshot {
+	m.mu.RLock()
+	snapshots := make([]MetricsSnapshot, 0, len(m.series))
+	for key, s := range m.series {
+		s.mu.Lock()
+		snapshot := MetricsSnapshot{
+			Table:     key.table,
+			Operation: key.operation,
+			Count:     s.count,
+			Errors:    s.errors,
+			Sum:       s.sum,
+			Buckets:   make([]MetricsBucket, len(s.buckets)),
+		}
+		var cumulative uint64
+		for i, n := range s.buckets {
+			cumulative += n
+			snapshot.Buckets[i] = MetricsBucket{UpperBound: m.conf.Buckets[i], Count: cumulative}
+		}
+		s.mu.Unlock()
+
+		snapshots = append(snapshots, snapshot)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		if snapshots[i].Table != snapshots[j].Table {
+			return snapshots[i].Table < snapshots[j].Table
+		}
+		return snapshots[i].Operation < snapshots[j].Operation
+	})
+
+	return snapshots
+}
+
+// String 实现 expvar.Var 接口，以 JSON 格式返回 Snapshot 的结果，可以通过 expvar.Publish("gorm", metrics) 发布。
+func (m *Metrics) String() string {
+	data, err := json.Marshal(m.Snapshot())
+	if err != nil {
+		return "null"
+	}
+
+	return string(data)
+}
+
+// WritePrometheus 以 Prometheus 文本格式输出所有指标。
+// 延迟直方图的名称为 {namespace}_query_duration_seconds，错误计数的名称为 {namespace}_query_errors_total。
+//
+// 参数:
+//   - w: 输出的目标。
+//
+// 返回值:
+//   - error: 写入失败时返回错误。
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	snapshots := m.Snapshot()
+	duration := m.conf.Namespace + "_query_duration_seconds"
+	errorsTotal := m.conf.Namespace + "_query_errors_total"
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s Latency of gorm operations by table and operation.\n", duration)
+	fmt.Fprintf(&b, "# TYPE %s histogram\n", duration)
+	for _, s := range snapshots {
+		labels := m.labels(s)
+		for _, bucket := range s.Buckets {
+			fmt.Fprintf(&b, "%s_bucket{%s,le=\"%s\"} %d\n", duration, labels, formatFloat(bucket.UpperBound), bucket.Count)
+		}
+		fmt.Fprintf(&b, "%s_bucket{%s,le=\"+Inf\"} %d\n", duration, labels, s.Count)
+		fmt.Fprintf(&b, "%s_sum{%s} %s\n", duration, labels, formatFloat(s.Sum))
+		fmt.Fprintf(&b, "%s_count{%s} %d\n", duration, labels, s.Count)
+	}
+
+	fmt.Fprintf(&b, "# HELP %s Failed gorm operations by table and operation.\n", errorsTotal)
+	fmt.Fprintf(&b, "# TYPE %s counter\n", errorsTotal)
+	for _, s := range snapshots {
+		fmt.Fprintf(&b, "%s{%s} %d\n", errorsTotal, m.labels(s), s.Errors)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ServeHTTP 实现 http.Handler 接口，以 Prometheus 文本格式输出所有指标，可以挂载到 /metrics。
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WritePrometheus(w)
+}
+
+// labels 返回一组指标的 Prometheus 标签，固定标签按名称排序在前。
+func (m *Metrics) labels(s MetricsSnapshot) string {
+	names := make([]string, 0, len(m.conf.ConstLabels))
+	for name := range m.conf.ConstLabels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s=\"%s\",", name, escapeLabel(m.conf.ConstLabels[name]))
+	}
+	fmt.Fprintf(&b, "table=\"%s\",operation=\"%s\"", escapeLabel(s.Table), s.Operation)
+
+	return b.String()
+}
+
+// escapeLabel 按 Prometheus 文本格式转义标签值。
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// formatFloat 按 Prometheus 文本格式输出浮点数。
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,106 @@
+package driver
+
+import (
+	"errors"
+	"expvar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// dryRunDialector 是只生成 SQL 的 Dialector，配合 DryRun 使用，不需要数据库连接。
+type dryRunDialector struct{}
+
+func (dryRunDialector) Name() string { return "dryrun" }
+
+func (dryRunDialector) Initialize(db *gorm.DB) error {
+	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{})
+	return nil
+}
+
+func (dryRunDialector) Migrator(*gorm.DB) gorm.Migrator                     { return nil }
+func (dryRunDialector) DataTypeOf(*schema.Field) string                     { return "" }
+func (dryRunDialector) DefaultValueOf(*schema.Field) clause.Expression      { return clause.Expr{} }
+func (dryRunDialector) BindVarTo(w clause.Writer, _ *gorm.Statement, _ any) { w.WriteByte('?') }
+func (dryRunDialector) QuoteTo(w clause.Writer, s string)                   { w.WriteString(s) }
+func (dryRunDialector) Explain(sql string, _ ...any) string                 { return sql }
+
+func TestMetrics(t *testing.T) {
+	db, err := gorm.Open(dryRunDialector{}, &gorm.Config{DryRun: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	metrics := NewMetrics(MetricsConfig{Buckets: []float64{1, 0.5}, ConstLabels: map[string]string{"db": `a"b`}})
+	if err := db.Use(metrics); err != nil {
+		t.Fatalf("use: %v", err)
+	}
+	// 模拟 orders 表的查询失败
+	db.Callback().Query().Before("gorm:query").Register("test:fail", func(db *gorm.DB) {
+		if db.Statement.Table == "orders" {
+			db.AddError(errors.New("boom"))
+		}
+	})
+
+	var rows []map[string]any
+	db.Table("users").Find(&rows)
+	db.Table("users").Find(&rows)
+	db.Table("orders").Find(&rows)
+	db.Table("users").Where("id = ?", 1).Delete(nil)
+	db.Exec("SELECT 1")
+
+	snapshots := metrics.Snapshot()
+	got := make(map[string]MetricsSnapshot)
+	for _, s := range snapshots {
+		got[s.Table+"/"+s.Operation] = s
+	}
+	if len(got) != 4 || got["users/query"].Count != 2 || got["orders/query"].Errors != 1 || got["users/delete"].Count != 1 || got["unknown/raw"].Count != 1 {
+		t.Fatalf("unexpected snapshots %+v", snapshots)
+	}
+	if buckets := got["users/query"].Buckets; len(buckets) != 2 || buckets[0].UpperBound != 0.5 || buckets[1].Count != 2 {
+		t.Fatalf("unexpected buckets %+v", buckets)
+	}
+
+	var _ expvar.Var = metrics
+	if s := metrics.String(); !strings.Contains(s, `"table":"orders","operation":"query","count":1,"errors":1`) {
+		t.Fatalf("unexpected expvar %s", s)
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE gorm_query_duration_seconds histogram\n",
+		`gorm_query_duration_seconds_bucket{db="a\"b",table="users",operation="query",le="0.5"} 2`,
+		`gorm_query_duration_seconds_bucket{db="a\"b",table="users",operation="query",le="+Inf"} 2`,
+		`gorm_query_duration_seconds_count{db="a\"b",table="unknown",operation="raw"} 1`,
+		`gorm_query_errors_total{db="a\"b",table="orders",operation="query"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("prometheus output missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetricsObserve(t *testing.T) {
+	metrics := NewMetrics(MetricsConfig{Buckets: []float64{0.1, 1}})
+	key := metricsKey{table: "users", operation: "query"}
+	metrics.observe(key, 50*time.Millisecond, false)
+	metrics.observe(key, 500*time.Millisecond, true)
+	metrics.observe(key, 5*time.Second, false)
+
+	s := metrics.Snapshot()[0]
+	if s.Count != 3 || s.Errors != 1 || s.Buckets[0].Count != 1 || s.Buckets[1].Count != 2 {
+		t.Fatalf("unexpected snapshot %+v", s)
+	}
+	if s.Sum < 5.5 || s.Sum > 5.6 {
+		t.Fatalf("sum = %v, want 5.55", s.Sum)
+	}
+}
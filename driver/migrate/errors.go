@@ -0,0 +1,25 @@
+package migrate
+
+import "errors"
+
+// 定义预定义错误，用于区分迁移失败的原因。
+// 返回的错误包含了具体的版本号，可以通过 errors.Is 判断其类型。
+var (
+	// ErrInvalidSource 表示迁移文件的名称或内容不符合要求，例如版本号重复或缺少 up 文件。
+	ErrInvalidSource = errors.New("invalid migration source")
+
+	// ErrChecksumMismatch 表示已执行的迁移文件在执行后被修改。
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+
+	// ErrMissingMigration 表示需要回滚的迁移已执行，但迁移文件不存在。
+	ErrMissingMigration = errors.New("migration file missing")
+
+	// ErrIrreversible 表示需要回滚的迁移没有 down 文件。
+	ErrIrreversible = errors.New("migration is irreversible")
+
+	// ErrUnknownVersion 表示 Goto 的目标版本不存在。
+	ErrUnknownVersion = errors.New("unknown migration version")
+
+	// ErrLocked 表示在 LockTimeout 内无法获取迁移锁，其他实例正在执行迁移。
+	ErrLocked = errors.New("migration lock is held by another session")
+)
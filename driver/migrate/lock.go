@@ -0,0 +1,125 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// lockPollInterval 是 PostgreSQL 重试获取迁移锁的间隔。
+const lockPollInterval = 500 * time.Millisecond
+
+// locker 定义数据库级别的迁移锁，锁与会话绑定，因此加锁与解锁必须在同一个连接上执行。
+type locker interface {
+	lock(ctx context.Context, conn gorm.ConnPool) error
+	unlock(ctx context.Context, conn gorm.ConnPool) error
+}
+
+// newLocker 根据数据库类型返回迁移锁。
+// MySQL 使用 GET_LOCK，PostgreSQL 使用 pg_advisory_lock，其他数据库不加锁。
+func newLocker(dialect, name string, timeout time.Duration) locker {
+	switch dialect {
+	case "mysql":
+		return mysqlLocker{name: name, timeout: timeout}
+	case "postgres":
+		h := fnv.New64a()
+		h.Write([]byte(name))
+		return postgresLocker{key: int64(h.Sum64()), timeout: timeout}
+	default:
+		return noopLocker{}
+	}
+}
+
+// mysqlLockName 是 MySQL 命名锁的名称表达式。
+// GET_LOCK 的锁在整个服务器范围内有效，因此在版本表名前加上当前数据库名，避免同一服务器上的不同数据库互相阻塞。
+const mysqlLockName = "CONCAT(IFNULL(DATABASE(), ''), '.', ?)"
+
+// mysqlLocker 使用 MySQL 的命名锁。
+type mysqlLocker struct {
+	name    string
+	timeout time.Duration
+}
+
+func (l mysqlLocker) lock(ctx context.Context, conn gorm.ConnPool) error {
+	// GET_LOCK 的超时时间以秒为单位，负数表示一直等待
+	seconds := -1
+	if l.timeout > 0 {
+		seconds = int(math.Ceil(l.timeout.Seconds()))
+	}
+
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK("+mysqlLockName+", ?)", l.name, seconds).Scan(&acquired); err != nil {
+		return fmt.Errorf("unable to acquire migration lock: %w", err)
+	}
+	if acquired.Int64 != 1 {
+		return fmt.Errorf("%w: %s", ErrLocked, l.name)
+	}
+
+	return nil
+}
+
+func (l mysqlLocker) unlock(ctx context.Context, conn gorm.ConnPool) error {
+	if _, err := conn.ExecContext(ctx, "DO RELEASE_LOCK("+mysqlLockName+")", l.name); err != nil {
+		return fmt.Errorf("unable to release migration lock: %w", err)
+	}
+
+	return nil
+}
+
+// postgresLocker 使用 PostgreSQL 的会话级咨询锁，咨询锁只在当前数据库内有效。
+// pg_advisory_lock 不支持超时，因此通过 pg_try_advisory_lock 轮询实现 LockTimeout。
+type postgresLocker struct {
+	key     int64
+	timeout time.Duration
+}
+
+func (l postgresLocker) lock(ctx context.Context, conn gorm.ConnPool) error {
+	if l.timeout <= 0 {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", l.key); err != nil {
+			return fmt.Errorf("unable to acquire migration lock: %w", err)
+		}
+		return nil
+	}
+
+	deadline := time.Now().Add(l.timeout)
+	for {
+		var acquired bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", l.key).Scan(&acquired); err != nil {
+			return fmt.Errorf("unable to acquire migration lock: %w", err)
+		}
+		if acquired {
+			return nil
+		}
+
+		if time.Now().Add(lockPollInterval).After(deadline) {
+			return fmt.Errorf("%w: advisory lock %d", ErrLocked, l.key)
+		}
+
+		timer := time.NewTimer(lockPollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("unable to acquire migration lock: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (l postgresLocker) unlock(ctx context.Context, conn gorm.ConnPool) error {
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.key); err != nil {
+		return fmt.Errorf("unable to release migration lock: %w", err)
+	}
+
+	return nil
+}
+
+// noopLocker 用于不支持咨询锁的数据库，例如 SQLite。
+type noopLocker struct{}
+
+func (noopLocker) lock(context.Context, gorm.ConnPool) error   { return nil }
+func (noopLocker) unlock(context.Context, gorm.ConnPool) error { return nil }
@@ -0,0 +1,145 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// recordedQuery 是 recordingConn 执行过的一条语句。
+type recordedQuery struct {
+	query string
+	args  []any
+}
+
+// recordingConn 记录执行的语句，查询时返回 result 作为唯一一行的唯一一列。
+type recordingConn struct {
+	result  sqldriver.Value
+	queries *[]recordedQuery
+}
+
+func (c recordingConn) record(query string, args []sqldriver.NamedValue) {
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	*c.queries = append(*c.queries, recordedQuery{query: query, args: values})
+}
+
+func (c recordingConn) QueryContext(_ context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Rows, error) {
+	c.record(query, args)
+	return &singleRow{value: c.result}, nil
+}
+
+func (c recordingConn) ExecContext(_ context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Result, error) {
+	c.record(query, args)
+	return sqldriver.RowsAffected(0), nil
+}
+
+func (recordingConn) Prepare(string) (sqldriver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+func (recordingConn) Close() error                 { return nil }
+func (recordingConn) Begin() (sqldriver.Tx, error) { return nil, errors.New("not implemented") }
+
+// recordingConnector 总是返回同一个 recordingConn 的连接器。
+type recordingConnector struct {
+	conn recordingConn
+}
+
+func (c recordingConnector) Connect(context.Context) (sqldriver.Conn, error) { return c.conn, nil }
+func (recordingConnector) Driver() sqldriver.Driver                          { return nil }
+
+// singleRow 是只有一行一列的结果集。
+type singleRow struct {
+	value sqldriver.Value
+	done  bool
+}
+
+func (r *singleRow) Columns() []string { return []string{"result"} }
+func (r *singleRow) Close() error      { return nil }
+
+func (r *singleRow) Next(dest []sqldriver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+	return nil
+}
+
+func TestLockers(t *testing.T) {
+	tests := []struct {
+		dialect string
+		timeout time.Duration
+		result  sqldriver.Value
+		want    []recordedQuery
+	}{
+		{
+			dialect: "mysql",
+			timeout: 1500 * time.Millisecond,
+			result:  int64(1),
+			want: []recordedQuery{
+				{query: "SELECT GET_LOCK(CONCAT(IFNULL(DATABASE(), ''), '.', ?), ?)", args: []any{"schema_migrations", int64(2)}},
+				{query: "DO RELEASE_LOCK(CONCAT(IFNULL(DATABASE(), ''), '.', ?))", args: []any{"schema_migrations"}},
+			},
+		},
+		{
+			dialect: "postgres",
+			result:  true,
+			want: []recordedQuery{
+				{query: "SELECT pg_advisory_lock($1)", args: []any{int64(-4387181548746815398)}},
+				{query: "SELECT pg_advisory_unlock($1)", args: []any{int64(-4387181548746815398)}},
+			},
+		},
+		{
+			dialect: "postgres",
+			timeout: time.Second,
+			result:  true,
+			want: []recordedQuery{
+				{query: "SELECT pg_try_advisory_lock($1)", args: []any{int64(-4387181548746815398)}},
+				{query: "SELECT pg_advisory_unlock($1)", args: []any{int64(-4387181548746815398)}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%s", tt.dialect, tt.timeout), func(t *testing.T) {
+			var queries []recordedQuery
+			db := sql.OpenDB(recordingConnector{conn: recordingConn{result: tt.result, queries: &queries}})
+			defer db.Close()
+
+			ctx := context.Background()
+			locker := newLocker(tt.dialect, "schema_migrations", tt.timeout)
+			if err := locker.lock(ctx, db); err != nil {
+				t.Fatalf("lock: %v", err)
+			}
+			if err := locker.unlock(ctx, db); err != nil {
+				t.Fatalf("unlock: %v", err)
+			}
+
+			if !reflect.DeepEqual(queries, tt.want) {
+				t.Fatalf("queries = %+v, want %+v", queries, tt.want)
+			}
+		})
+	}
+}
+
+func TestMySQLLockerBusy(t *testing.T) {
+	var queries []recordedQuery
+	db := sql.OpenDB(recordingConnector{conn: recordingConn{result: int64(0), queries: &queries}})
+	defer db.Close()
+
+	if err := newLocker("mysql", "schema_migrations", 0).lock(context.Background(), db); !errors.Is(err, ErrLocked) {
+		t.Fatalf("lock error = %v, want ErrLocked", err)
+	}
+	if len(queries) != 1 || queries[0].args[1] != int64(-1) {
+		t.Fatalf("unexpected queries %+v", queries)
+	}
+}
@@ -0,0 +1,352 @@
+// Package migrate 提供基于版本号的 SQL 迁移，迁移通过 gorm.DB 执行，因此同样适用于通过 SSH 隧道连接的数据库。
+//
+// 迁移文件的名称为 {version}_{name}.up.sql 与 {version}_{name}.down.sql，可以从 embed.FS 或 os.DirFS 读取。
+// 已执行的版本及其文件的校验和记录在 schema_migrations 表中，执行期间持有数据库级别的咨询锁，
+// 多个实例同时启动时只有一个实例执行迁移。
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 迁移的默认配置。
+const (
+	DefaultTable       = "schema_migrations" // 记录已执行版本的默认表名
+	DefaultLockTimeout = time.Minute         // 等待迁移锁的默认时长
+)
+
+// Options 定义迁移的配置选项。
+type Options struct {
+	Dir         string        // 迁移文件所在的目录，默认为 "."，例如使用 //go:embed migrations 时为 "migrations"。
+	Table       string        // 记录已执行版本的表名，默认为 DefaultTable。
+	LockTimeout time.Duration // 等待迁移锁的时长，默认为 DefaultLockTimeout，小于 0 时一直等待。
+	Logger      *slog.Logger  // 记录每个迁移执行情况的日志记录器，为 nil 时使用 slog.Default()。
+}
+
+// Status 定义一个版本的迁移状态。
+type Status struct {
+	Version   uint64    // 版本号
+	Name      string    // 迁移名称
+	Applied   bool      // 是否已执行
+	AppliedAt time.Time // 执行时间，未执行时为零值
+	Modified  bool      // 已执行的 up 文件在执行后被修改
+	Missing   bool      // 已执行但迁移文件不存在
+}
+
+// Migrator 执行 SQL 迁移，可以安全地并发调用。
+type Migrator struct {
+	db         *gorm.DB
+	migrations []migration
+	table      string
+	locker     locker
+	logger     *slog.Logger
+}
+
+// schemaMigration 是迁移记录表的模型。
+type schemaMigration struct {
+	Version   uint64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:255;not null"`
+	Checksum  string `gorm:"size:64;not null"`
+	AppliedAt time.Time
+}
+
+// New 创建一个迁移执行器，并读取所有的迁移文件。
+//
+// 参数:
+//   - db: 数据库连接，可以是 mysql.OpenDB 或 postgres.OpenDB 返回的连接。
+//   - fsys: 迁移文件所在的文件系统，例如 embed.FS 或 os.DirFS("migrations")。
+//   - opts: 配置选项。
+//
+// 返回值:
+//   - *Migrator: 迁移执行器。
+//   - error: 如果迁移文件无法读取或不符合命名要求，则返回错误。
+func New(db *gorm.DB, fsys fs.FS, opts Options) (*Migrator, error) {
+	dir := opts.Dir
+	if dir == "" {
+		dir = "."
+	}
+
+	migrations, err := load(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	table := opts.Table
+	if table == "" {
+		table = DefaultTable
+	}
+
+	lockTimeout := opts.LockTimeout
+	if lockTimeout == 0 {
+		lockTimeout = DefaultLockTimeout
+	}
+
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		table:      table,
+		locker:     newLocker(db.Dialector.Name(), table, lockTimeout),
+		logger:     logger,
+	}, nil
+}
+
+// Up 按版本号升序执行所有未执行的迁移。
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.run(ctx, func(conn *gorm.DB, applied map[uint64]schemaMigration) error {
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.version]; ok {
+				continue
+			}
+			if err := m.apply(conn, mig); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down 按版本号降序回滚最近执行的 n 个迁移，n 大于已执行的数量时回滚全部。
+//
+// 返回值:
+//   - error: 如果需要回滚的迁移没有 down 文件或迁移文件不存在，则返回 ErrIrreversible 或 ErrMissingMigration，之前的迁移不会被回滚。
+func (m *Migrator) Down(ctx context.Context, n int) error {
+	if n <= 0 {
+		return nil
+	}
+
+	return m.run(ctx, func(conn *gorm.DB, applied map[uint64]schemaMigration) error {
+		versions := make([]uint64, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		return m.revertAll(conn, versions[:min(n, len(versions))])
+	})
+}
+
+// Goto 迁移到指定的版本：执行该版本及之前所有未执行的迁移，回滚该版本之后所有已执行的迁移。
+//
+// 参数:
+//   - version: 目标版本，为 0 时回滚全部迁移。
+//
+// 返回值:
+//   - error: 如果目标版本不存在，则返回 ErrUnknownVersion。
+func (m *Migrator) Goto(ctx context.Context, version uint64) error {
+	if _, ok := m.find(version); !ok && version != 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	return m.run(ctx, func(conn *gorm.DB, applied map[uint64]schemaMigration) error {
+		var newer []uint64
+		for v := range applied {
+			if v > version {
+				newer = append(newer, v)
+			}
+		}
+		sort.Slice(newer, func(i, j int) bool { return newer[i] > newer[j] })
+
+		if err := m.revertAll(conn, newer); err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.version]; ok || mig.version > version {
+				continue
+			}
+			if err := m.apply(conn, mig); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Status 返回所有迁移的状态，按版本号升序排列，包括已执行但迁移文件不存在的版本。
+// Status 只读取迁移记录，不获取迁移锁，也不会创建迁移记录表。
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var applied map[uint64]schemaMigration
+	// 在事务中读取，读写分离时同样从主库读取
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if !tx.Migrator().HasTable(m.table) {
+			return nil
+		}
+
+		var err error
+		applied, err = m.applied(tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := Status{Version: mig.version, Name: mig.name}
+		if record, ok := applied[mig.version]; ok {
+			s.Applied, s.AppliedAt, s.Modified = true, record.AppliedAt, record.Checksum != mig.checksum
+		}
+		statuses = append(statuses, s)
+	}
+	for version, record := range applied {
+		if _, ok := m.find(version); !ok {
+			statuses = append(statuses, Status{Version: version, Name: record.Name, Applied: true, AppliedAt: record.AppliedAt, Missing: true})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
+
+// run 在单独的连接上获取迁移锁，创建迁移记录表并校验已执行迁移的校验和后执行 fn。
+func (m *Migrator) run(ctx context.Context, fn func(conn *gorm.DB, applied map[uint64]schemaMigration) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) (err error) {
+		// 咨询锁与会话绑定，直接在固定的连接上执行，避免被读写分离路由到只读副本
+		pool := conn.Statement.ConnPool
+		if err := m.locker.lock(ctx, pool); err != nil {
+			return err
+		}
+		defer func() {
+			// ctx 被取消后仍然需要释放锁，否则连接归还连接池后锁会一直被持有
+			if unlockErr := m.locker.unlock(context.WithoutCancel(ctx), pool); unlockErr != nil {
+				err = errors.Join(err, unlockErr)
+			}
+		}()
+
+		var applied map[uint64]schemaMigration
+		err = conn.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Table(m.table).AutoMigrate(&schemaMigration{}); err != nil {
+				return fmt.Errorf("unable to create migration table: %w", err)
+			}
+
+			var err error
+			applied, err = m.applied(tx)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		for version, record := range applied {
+			if mig, ok := m.find(version); ok && mig.checksum != record.Checksum {
+				return fmt.Errorf("%w: version %d (%s) was modified after it was applied", ErrChecksumMismatch, version, mig.name)
+			}
+		}
+
+		return fn(conn, applied)
+	})
+}
+
+// applied 读取所有已执行的迁移记录。
+func (m *Migrator) applied(tx *gorm.DB) (map[uint64]schemaMigration, error) {
+	var records []schemaMigration
+	if err := tx.Table(m.table).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("unable to read migration table: %w", err)
+	}
+
+	applied := make(map[uint64]schemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+
+	return applied, nil
+}
+
+// apply 在事务中执行迁移的 up 文件并写入迁移记录。
+// MySQL 的 DDL 语句会隐式提交事务，失败时已执行的语句不会被回滚，需要手动修复后重新执行。
+func (m *Migrator) apply(conn *gorm.DB, mig migration) error {
+	start := time.Now()
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := m.exec(tx, mig.up); err != nil {
+			return err
+		}
+
+		record := schemaMigration{Version: mig.version, Name: mig.name, Checksum: mig.checksum, AppliedAt: time.Now()}
+		return tx.Table(m.table).Create(&record).Error
+	})
+	if err != nil {
+		m.logger.Error("migration failed", slog.Uint64("version", mig.version), slog.String("name", mig.name), slog.Any("error", err))
+		return fmt.Errorf("migration %d (%s) up failed: %w", mig.version, mig.name, err)
+	}
+
+	m.logger.Info("migration applied", slog.Uint64("version", mig.version), slog.String("name", mig.name), slog.Duration("duration", time.Since(start)))
+	return nil
+}
+
+// revertAll 按给定的顺序回滚迁移，开始前检查所有迁移均可回滚。
+func (m *Migrator) revertAll(conn *gorm.DB, versions []uint64) error {
+	migrations := make([]migration, 0, len(versions))
+	for _, version := range versions {
+		mig, ok := m.find(version)
+		if !ok {
+			return fmt.Errorf("%w: version %d", ErrMissingMigration, version)
+		}
+		if !mig.hasDown {
+			return fmt.Errorf("%w: version %d (%s) has no down file", ErrIrreversible, version, mig.name)
+		}
+		migrations = append(migrations, mig)
+	}
+
+	for _, mig := range migrations {
+		if err := m.revert(conn, mig); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revert 在事务中执行迁移的 down 文件并删除迁移记录。
+func (m *Migrator) revert(conn *gorm.DB, mig migration) error {
+	start := time.Now()
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := m.exec(tx, mig.down); err != nil {
+			return err
+		}
+
+		return tx.Table(m.table).Where("version = ?", mig.version).Delete(&schemaMigration{}).Error
+	})
+	if err != nil {
+		m.logger.Error("migration revert failed", slog.Uint64("version", mig.version), slog.String("name", mig.name), slog.Any("error", err))
+		return fmt.Errorf("migration %d (%s) down failed: %w", mig.version, mig.name, err)
+	}
+
+	m.logger.Info("migration reverted", slog.Uint64("version", mig.version), slog.String("name", mig.name), slog.Duration("duration", time.Since(start)))
+	return nil
+}
+
+// exec 逐条执行迁移文件中的语句。
+// 语句直接在事务上执行，不经过 Gorm 的占位符替换，语句中的 ? 不会被当作参数。
+func (m *Migrator) exec(tx *gorm.DB, sql string) error {
+	ctx := tx.Statement.Context
+	for _, stmt := range splitStatements(sql, m.db.Dialector.Name() == "mysql") {
+		if _, err := tx.Statement.ConnPool.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// find 返回指定版本的迁移。
+func (m *Migrator) find(version uint64) (migration, bool) {
+	i := sort.Search(len(m.migrations), func(i int) bool { return m.migrations[i].version >= version })
+	if i < len(m.migrations) && m.migrations[i].version == version {
+		return m.migrations[i], true
+	}
+
+	return migration{}, false
+}
@@ -0,0 +1,230 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testFS 是测试使用的迁移文件。
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"migrations/0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);\n-- 初始用户\nINSERT INTO users (name) VALUES ('a;b');")},
+		"migrations/0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"migrations/0002_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT;")},
+		"migrations/0002_add_email.down.sql":    {Data: []byte("ALTER TABLE users DROP COLUMN email;")},
+		"migrations/0003_create_posts.up.sql":   {Data: []byte("CREATE TABLE posts (id INTEGER PRIMARY KEY);")},
+		"migrations/0003_create_posts.down.sql": {Data: []byte("DROP TABLE posts;")},
+		"migrations/README.md":                  {Data: []byte("ignored")},
+	}
+}
+
+// openDB 打开一个临时的 SQLite 数据库，使用文件以便多个连接访问同一个数据库。
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	return db
+}
+
+// applied 返回已执行的版本号。
+func applied(t *testing.T, m *Migrator) []uint64 {
+	t.Helper()
+
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+
+	versions := []uint64{}
+	for _, s := range statuses {
+		if s.Applied {
+			versions = append(versions, s.Version)
+		}
+	}
+
+	return versions
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	m, err := New(db, testFS(), Options{Dir: "migrations", Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	if got := applied(t, m); len(got) != 0 {
+		t.Fatalf("applied before up = %v, want none", got)
+	}
+	if db.Migrator().HasTable(DefaultTable) {
+		t.Fatal("status created the migration table")
+	}
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if got, want := applied(t, m), []uint64{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("applied after up = %v, want %v", got, want)
+	}
+
+	var name string
+	if err := db.Raw("SELECT name FROM users").Scan(&name).Error; err != nil || name != "a;b" {
+		t.Fatalf("users.name = %q, %v, want %q", name, err, "a;b")
+	}
+
+	// 重复执行不会有任何变化
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("second up: %v", err)
+	}
+
+	if err := m.Down(ctx, 2); err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if got, want := applied(t, m), []uint64{1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("applied after down 2 = %v, want %v", got, want)
+	}
+	if db.Migrator().HasTable("posts") {
+		t.Fatal("posts still exists after down")
+	}
+
+	if err := m.Goto(ctx, 2); err != nil {
+		t.Fatalf("goto 2: %v", err)
+	}
+	if got, want := applied(t, m), []uint64{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("applied after goto 2 = %v, want %v", got, want)
+	}
+
+	if err := m.Goto(ctx, 0); err != nil {
+		t.Fatalf("goto 0: %v", err)
+	}
+	if got := applied(t, m); len(got) != 0 {
+		t.Fatalf("applied after goto 0 = %v, want none", got)
+	}
+
+	if err := m.Goto(ctx, 9); !errors.Is(err, ErrUnknownVersion) {
+		t.Fatalf("goto 9 error = %v, want ErrUnknownVersion", err)
+	}
+}
+
+func TestMigratorChecks(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	fsys := testFS()
+
+	m, err := New(db, fsys, Options{Dir: "migrations", Table: "versions"})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+
+	// 修改已执行的迁移文件
+	fsys["migrations/0002_add_email.up.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE users ADD COLUMN mail TEXT;")}
+	modified, err := New(db, fsys, Options{Dir: "migrations", Table: "versions"})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if err := modified.Up(ctx); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("up error = %v, want ErrChecksumMismatch", err)
+	}
+	statuses, err := modified.Status(ctx)
+	if err != nil || !statuses[1].Modified {
+		t.Fatalf("status = %+v, %v, want version 2 modified", statuses, err)
+	}
+
+	// 删除已执行的迁移文件，并去掉 down 文件
+	partial := fstest.MapFS{
+		"0001_create_users.up.sql":   fsys["migrations/0001_create_users.up.sql"],
+		"0001_create_users.down.sql": fsys["migrations/0001_create_users.down.sql"],
+		"0002_add_email.up.sql":      testFS()["migrations/0002_add_email.up.sql"],
+	}
+	missing, err := New(db, partial, Options{Table: "versions"})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if err := missing.Down(ctx, 1); !errors.Is(err, ErrMissingMigration) {
+		t.Fatalf("down error = %v, want ErrMissingMigration", err)
+	}
+	statuses, err = missing.Status(ctx)
+	if err != nil || len(statuses) != 3 || !statuses[2].Missing {
+		t.Fatalf("status = %+v, %v, want version 3 missing", statuses, err)
+	}
+
+	if err := db.Exec("DELETE FROM versions WHERE version = 3").Error; err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := missing.Down(ctx, 1); !errors.Is(err, ErrIrreversible) {
+		t.Fatalf("down error = %v, want ErrIrreversible", err)
+	}
+	if got, want := applied(t, missing), []uint64{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("applied after failed down = %v, want %v", got, want)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"no up file", fstest.MapFS{"0001_a.down.sql": {}}},
+		{"duplicate version", fstest.MapFS{"0001_a.up.sql": {}, "1_a.up.sql": {}}},
+		{"different names", fstest.MapFS{"0001_a.up.sql": {}, "0001_b.down.sql": {}}},
+		{"missing direction", fstest.MapFS{"0001_a.sql": {}}},
+		{"missing name", fstest.MapFS{"0001.up.sql": {}}},
+		{"zero version", fstest.MapFS{"0_a.up.sql": {}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := load(tt.fsys, "."); !errors.Is(err, ErrInvalidSource) {
+				t.Fatalf("load error = %v, want ErrInvalidSource", err)
+			}
+		})
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name  string
+		sql   string
+		mysql bool
+		want  []string
+	}{
+		{"simple", "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\n", false, []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"}},
+		{"no trailing semicolon", "SELECT 1", false, []string{"SELECT 1"}},
+		{"quoted", `INSERT INTO a VALUES ('x;y', "c;d", 'it''s;');`, false, []string{`INSERT INTO a VALUES ('x;y', "c;d", 'it''s;')`}},
+		{"comments", "-- a; b\nSELECT 1; /* c; d */ SELECT 2;\n-- trailing;", false, []string{"-- a; b\nSELECT 1", "/* c; d */ SELECT 2"}},
+		{"dollar quoted", "CREATE FUNCTION f() RETURNS int AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql; SELECT $1", false, []string{"CREATE FUNCTION f() RETURNS int AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql", "SELECT $1"}},
+		{"mysql backslash", `INSERT INTO a VALUES ('x\';y'); # c; d` + "\nSELECT 2", true, []string{`INSERT INTO a VALUES ('x\';y')`, "# c; d\nSELECT 2"}},
+		{"only comments", "-- nothing\n", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitStatements(tt.sql, tt.mysql); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("splitStatements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migration 定义一个版本的迁移。
+type migration struct {
+	version  uint64
+	name     string
+	up       string
+	down     string
+	hasDown  bool
+	checksum string
+}
+
+// load 读取目录中的迁移文件，返回按版本号升序排列的迁移。
+// 文件名的格式为 {version}_{name}.up.sql 与 {version}_{name}.down.sql，例如 0001_create_users.up.sql，
+// 其他扩展名的文件会被忽略。
+//
+// 参数:
+//   - fsys: 迁移文件所在的文件系统。
+//   - dir: 迁移文件所在的目录。
+//
+// 返回值:
+//   - []migration: 按版本号升序排列的迁移。
+//   - error: 如果文件无法读取或不符合命名要求，则返回错误。
+func load(fsys fs.FS, dir string) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read migration directory: %w", err)
+	}
+
+	byVersion := make(map[uint64]*migration)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		version, name, up, err := parseFileName(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read migration file: %w", err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: name}
+			byVersion[version] = m
+		}
+		if m.name != name {
+			return nil, fmt.Errorf("%w: version %d has different names %q and %q", ErrInvalidSource, version, m.name, name)
+		}
+
+		if up {
+			if m.checksum != "" {
+				return nil, fmt.Errorf("%w: duplicate up file for version %d", ErrInvalidSource, version)
+			}
+			sum := sha256.Sum256(data)
+			m.up, m.checksum = string(data), hex.EncodeToString(sum[:])
+		} else {
+			if m.hasDown {
+				return nil, fmt.Errorf("%w: duplicate down file for version %d", ErrInvalidSource, version)
+			}
+			m.down, m.hasDown = string(data), true
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.checksum == "" {
+			return nil, fmt.Errorf("%w: version %d has no up file", ErrInvalidSource, m.version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+
+	return migrations, nil
+}
+
+// parseFileName 解析迁移文件名，返回版本号、名称以及是否为 up 文件。
+func parseFileName(fileName string) (uint64, string, bool, error) {
+	base := strings.TrimSuffix(fileName, ".sql")
+
+	var up bool
+	switch {
+	case strings.HasSuffix(base, ".up"):
+		base, up = strings.TrimSuffix(base, ".up"), true
+	case strings.HasSuffix(base, ".down"):
+		base = strings.TrimSuffix(base, ".down")
+	default:
+		return 0, "", false, fmt.Errorf("%w: %q must end with .up.sql or .down.sql", ErrInvalidSource, fileName)
+	}
+
+	prefix, name, ok := strings.Cut(base, "_")
+	if !ok || name == "" {
+		return 0, "", false, fmt.Errorf("%w: %q must be named {version}_{name}", ErrInvalidSource, fileName)
+	}
+
+	version, err := strconv.ParseUint(prefix, 10, 64)
+	if err != nil || version == 0 {
+		return 0, "", false, fmt.Errorf("%w: %q must start with a positive version number", ErrInvalidSource, fileName)
+	}
+
+	return version, name, up, nil
+}
+
+// splitStatements 将迁移文件拆分为单独的语句，分号位于字符串、标识符、注释或 PostgreSQL 的 $$ 块中时不作为分隔符。
+// MySQL 默认不允许一次执行多条语句，因此每条语句单独执行。
+// 不支持 MySQL 客户端的 DELIMITER 命令，存储过程需要放在单独的迁移文件中并且不以分号分隔。
+//
+// 参数:
+//   - sql: 迁移文件的内容。
+//   - mysql: 是否按 MySQL 的语法解析，即字符串中的反斜杠为转义字符、# 开头为注释、不支持 $$ 块。
+//
+// 返回值:
+//   - []string: 去掉首尾空白后的语句，只包含注释的语句会被忽略。
+func splitStatements(sql string, mysql bool) []string {
+	var (
+		stmts   []string
+		start   int
+		hasCode bool
+	)
+
+	flush := func(end int) {
+		if hasCode {
+			stmts = append(stmts, strings.TrimSpace(sql[start:end]))
+		}
+		start, hasCode = end+1, false
+	}
+
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == ';':
+			flush(i)
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(sql, i, mysql)
+			hasCode = true
+		case strings.HasPrefix(sql[i:], "--") || (mysql && c == '#'):
+			if end := strings.IndexByte(sql[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(sql)
+			}
+		case strings.HasPrefix(sql[i:], "/*"):
+			if end := strings.Index(sql[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(sql)
+			}
+		case c == '$' && !mysql:
+			hasCode = true
+			tag := dollarTag(sql[i:])
+			if tag == "" {
+				continue
+			}
+			if end := strings.Index(sql[i+len(tag):], tag); end >= 0 {
+				i += end + 2*len(tag) - 1
+			} else {
+				i = len(sql)
+			}
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			hasCode = true
+		}
+	}
+	flush(len(sql))
+
+	return stmts
+}
+
+// skipQuoted 跳过从 start 开始的字符串或标识符，返回结束引号的位置。
+// 连续的两个引号会被当作结束后重新开始，因此无需特殊处理。
+func skipQuoted(sql string, start int, backslash bool) int {
+	quote := sql[start]
+	for i := start + 1; i < len(sql); i++ {
+		switch {
+		case backslash && quote != '`' && sql[i] == '\\':
+			i++
+		case sql[i] == quote:
+			return i
+		}
+	}
+
+	return len(sql)
+}
+
+// dollarTag 返回 s 开头的 PostgreSQL 美元引号标签，例如 $$ 或 $body$，不是标签时返回空字符串。
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80:
+		case c >= '0' && c <= '9' && i > 1:
+		default:
+			return ""
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,45 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/cotton-go/pkg/driver"
+	mysqld "github.com/go-sql-driver/mysql"
+)
+
+// openWithCredentials 打开一个每次建立新连接时从凭据提供者获取用户名与密码的连接池。
+//
+// 参数:
+//   - dsnConf: 结构化的 DSN 配置，其中的密码会被凭据提供者返回的密码替换。
+//   - provider: 凭据提供者。
+//
+// 返回值:
+//   - *sql.DB: 数据库连接池。
+//   - error: 如果 DSN 配置无效，则返回包装了 ErrDSNParse 的错误。
+func openWithCredentials(dsnConf *mysqld.Config, provider driver.CredentialProvider) (*sql.DB, error) {
+	dsnConf = dsnConf.Clone()
+	err := dsnConf.Apply(mysqld.BeforeConnect(func(ctx context.Context, cfg *mysqld.Config) error {
+		creds, err := provider.Credentials(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to get mysql credentials: %w", err)
+		}
+
+		if creds.User != "" {
+			cfg.User = creds.User
+		}
+		cfg.Passwd = creds.Password
+		return nil
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDSNParse, err)
+	}
+
+	connector, err := mysqld.NewConnector(dsnConf)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDSNParse, err)
+	}
+
+	return sql.OpenDB(connector), nil
+}
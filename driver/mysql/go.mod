@@ -1,6 +1,6 @@
 module github.com/cotton-go/pkg/driver/mysql
 
-go 1.17
+go 1.21
 
 require (
 	github.com/cotton-go/pkg/ssh v0.0.0-20240816034421-034ecfc24f77
@@ -9,13 +9,25 @@ require (
 	gorm.io/gorm v1.25.11
 )
 
+require (
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+	gorm.io/plugin/dbresolver v1.5.2 // indirect
+)
+
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
+	github.com/cotton-go/pkg/driver v0.0.0
+	github.com/cotton-go/pkg/driver/resolver v0.0.0
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
-	github.com/lib/pq v1.10.9 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	golang.org/x/crypto v0.26.0 // indirect
 	golang.org/x/sys v0.23.0 // indirect
 	golang.org/x/text v0.17.0 // indirect
 )
+
+replace github.com/cotton-go/pkg/ssh => ../../ssh
+
+replace github.com/cotton-go/pkg/driver/resolver => ../resolver
+
+replace github.com/cotton-go/pkg/driver => ../
@@ -2,10 +2,13 @@ package mysql
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"net"
-	"strings"
+	"time"
 
+	"github.com/cotton-go/pkg/driver"
+	"github.com/cotton-go/pkg/driver/resolver"
 	"github.com/cotton-go/pkg/ssh"
 	mysqld "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
@@ -32,12 +35,23 @@ type Config struct {
 	// As of MySQL 8.0.19, ALTER TABLE permits more general (and SQL standard) syntax
 	// for dropping and altering existing constraints of any type.
 	// see https://dev.mysql.com/doc/refman/8.0/en/alter-table.html
-	DontSupportDropConstraint bool        `json:"dontSupportDropConstraint"` // 不支持使用 DROP CONSTRAINT 语法来删除约束，默认为 false。
-	SSH                       *ssh.Config `json:"ssh,omitempty"`             // SSH 配置选项，默认为 nil。
+	DontSupportDropConstraint bool                      `json:"dontSupportDropConstraint"` // 不支持使用 DROP CONSTRAINT 语法来删除约束，默认为 false。
+	SSH                       *ssh.Config               `json:"ssh,omitempty"`             // SSH 配置选项，默认为 nil。
+	Replicas                  []Config                  `json:"replicas,omitempty"`        // 只读副本的配置，每个副本可以使用独立的 SSH 配置，通过 NewResolver 使用，默认为空。
+	ReplicaPolicy             resolver.Policy           `json:"replicaPolicy,omitempty"`   // 选择只读副本的策略，默认为随机。
+	MaxOpenConns              int                       `json:"maxOpenConns,omitempty"`    // 最大打开连接数，默认为 0，即不限制。
+	MaxIdleConns              int                       `json:"maxIdleConns,omitempty"`    // 最大空闲连接数，默认为 0，即 database/sql 的默认值 2，小于 0 时不保留空闲连接。
+	ConnMaxLifetime           time.Duration             `json:"connMaxLifetime,omitempty"` // 连接的最长使用时间，默认为 0，即不限制，使用 SSH 时默认为 5 分钟，小于 0 时不限制。
+	ConnMaxIdleTime           time.Duration             `json:"connMaxIdleTime,omitempty"` // 连接的最长空闲时间，默认为 0，即不限制，使用 SSH 时默认为 1 分钟，小于 0 时不限制。
+	Retry                     driver.Retry              `json:"retry,omitempty"`           // 启动时建立 SSH 连接与首次 Ping 的重试策略，默认不重试。
+	TLS                       *TLS                      `json:"tls,omitempty"`             // TLS 配置，设置后覆盖 DSN 中的 tls 参数，默认为 nil。
+	Credentials               driver.CredentialProvider `json:"-"`                         // 凭据提供者，每次建立新连接时获取用户名与密码，用于密码定期轮换的场景，默认为 nil。
+	Metrics                   *driver.Metrics           `json:"-"`                         // 查询指标插件，OpenDB 时注册，默认为 nil。
 }
 
 // New 根据配置创建一个新的 Gorm 数据库连接。
 // 它支持通过 SSH 隧道进行数据库连接，如果配置中提供了 SSH 配置。
+// 如果 DSN 无法解析或 SSH 连接失败，New 会直接 panic，需要处理错误时请使用 NewWithError。
 //
 // 参数:
 //   - conf: 数据库和 SSH 连接的配置。
@@ -45,28 +59,207 @@ type Config struct {
 // 返回值:
 //   - gorm.Dialector: 用于 Gorm 以建立数据库连接的接口。
 func New(conf Config) gorm.Dialector {
-	// 检查是否提供了 SSH 配置，如果提供了，则尝试建立 SSH 连接。
-	if sshConf := conf.SSH; sshConf != nil {
-		// 使用提供的 SSH 配置建立连接。
-		conn, err := ssh.Connect(*sshConf)
+	dialector, err := NewWithError(conf)
+	if err != nil {
+		// 如果创建失败，抛出异常。
+		panic(err)
+	}
+
+	return dialector
+}
+
+// NewWithError 与 New 相同，但在失败时返回错误而不是 panic。
+//
+// 参数:
+//   - conf: 数据库和 SSH 连接的配置。
+//
+// 返回值:
+//   - gorm.Dialector: 用于 Gorm 以建立数据库连接的接口。
+//   - error: 创建失败时返回的错误，可以通过 errors.Is 与 ErrSSHDial、ErrSSHAuth、ErrDSNParse、ErrTLSConfig 比较。
+func NewWithError(conf Config) (gorm.Dialector, error) {
+	ctx, cancel := conf.Retry.Context(context.Background())
+	defer cancel()
+
+	conf, err := conf.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dialector, err := conf.dialector()
+	if err != nil {
+		// 释放本次获取的 SSH 连接引用。
+		_ = closeTunnels(conf)
+		return nil, err
+	}
+
+	return dialector, nil
+}
+
+// prepare 解析 DSN，获取共享的 SSH 连接并在连接建立之后注册 TLS 配置，返回 DSN 指向 SSH 隧道的配置。
+// 成功时占用一次 SSH 连接引用，需要通过 closeTunnels 释放，ctx 限制建立 SSH 连接的总时长。
+func (c Config) prepare(ctx context.Context) (Config, error) {
+	// 解析 DSN，得到结构化的配置，同时避免在 gorm.Open 时才发现格式错误。
+	dsnConf, err := c.dsnConfig()
+	if err != nil {
+		return c, err
+	}
+
+	// 先读取证书校验 TLS 配置，SSH 连接建立之后再注册，避免连接失败时遗留注册的配置。
+	var tlsConf *tls.Config
+	if c.TLS != nil {
+		if dsnConf == nil {
+			return c, fmt.Errorf("%w: DSN or DSNConfig is required when TLS is set", ErrDSNParse)
+		}
+
+		if tlsConf, err = c.TLS.config(); err != nil {
+			return c, err
+		}
+	}
+
+	// 检查是否提供了 SSH 配置，如果提供了，则获取共享的 SSH 连接。
+	if sshConf := c.SSH; sshConf != nil {
+		if dsnConf == nil {
+			return c, fmt.Errorf("%w: DSN or DSNConfig is required when SSH is set", ErrDSNParse)
+		}
+
+		// 复制一份配置，避免修改调用方传入的 DSNConfig。
+		dsnConf = dsnConf.Clone()
+		if !isTunnelNet(dsnConf.Net) {
+			return c, fmt.Errorf("%w: network %q can not be tunneled through SSH", ErrDSNParse, dsnConf.Net)
+		}
+
+		// 获取 SSH 连接并注册拨号函数，相同配置只建立一次连接。
+		key, err := connectTunnel(ctx, *sshConf, c.Retry)
 		if err != nil {
-			// 如果连接失败，抛出异常。
-			panic(err)
+			// 如果连接失败，返回错误，错误类型为 ErrSSHDial 或 ErrSSHAuth。
+			return c, err
 		}
 
-		// 生成一个唯一的键，用于标识这个 SSH 连接。
-		key := fmt.Sprintf("%s-%d-%d-%s", sshConf.Host, sshConf.Port, sshConf.Type, sshConf.User)
-		// 注册一个自定义的拨号函数，使用 SSH 连接来拨号。
-		mysqld.RegisterDialContext(key, func(ctx context.Context, addr string) (net.Conn, error) {
-			return conn.Client().DialContext(ctx, "tcp", addr)
-		})
+		// 将网络类型替换为 SSH 隧道的标识符，unix 套接字会转发到远程主机上的套接字文件。
+		dsnConf.Net = tunnelNet(key, dsnConf.Net)
+		c.DSNConfig = dsnConf
+		c.DSN = dsnConf.FormatDSN()
+	}
+
+	// 注册 TLS 配置，并在 DSN 中引用注册的名称。
+	if tlsConf != nil {
+		name, err := c.TLS.register(tlsConf)
+		if err != nil {
+			// 释放刚刚获取的 SSH 连接引用
+			_ = closeTunnels(c)
+			return c, err
+		}
+
+		// 复制一份配置，避免修改调用方传入的 DSNConfig。
+		dsnConf = dsnConf.Clone()
+		dsnConf.TLSConfig = name
+		c.DSNConfig = dsnConf
+		c.DSN = dsnConf.FormatDSN()
+	}
+
+	return c, nil
+}
+
+// dialector 基于 prepare 返回的配置创建 MySQL 数据库连接器。
+// 设置了凭据提供者时每次调用都会打开新的连接池，因此可以在 gorm.Open 失败后重新调用。
+func (c Config) dialector() (gorm.Dialector, error) {
+	// 每次建立新连接时从凭据提供者获取用户名与密码。
+	if c.Credentials != nil && c.Conn == nil {
+		dsnConf, err := c.dsnConfig()
+		if err != nil {
+			return nil, err
+		}
+		if dsnConf == nil {
+			return nil, fmt.Errorf("%w: DSN or DSNConfig is required when Credentials is set", ErrDSNParse)
+		}
 
-		// 修改 DSN，将 SSH 隧道的标识符替换进去。
-		conf.DSN = strings.Replace(conf.DSN, "@tcp(", fmt.Sprintf("@%s(", key), 1)
+		db, err := openWithCredentials(dsnConf, c.Credentials)
+		if err != nil {
+			return nil, err
+		}
+		c.Conn = db
 	}
 
 	// 最终，基于配置创建并返回 MySQL 数据库连接器。
-	return mysql.New(conf.config())
+	return mysql.New(c.config()), nil
+}
+
+// OpenDB 根据配置打开数据库，并按配置中的 MaxOpenConns 等参数设置连接池。
+// 配置了 Replicas 时同时注册读写分离插件，只读副本使用相同的连接池参数。
+// 配置了 Metrics 时同时注册查询指标插件。
+// 关闭返回的数据库不会释放 SSH 隧道，需要同时释放时使用 driver.OpenHandle(conf) 或 Config.Close。
+//
+// 参数:
+//   - conf: 数据库和 SSH 连接的配置。
+//   - opts: 传递给 gorm.Open 的选项。
+//
+// 返回值:
+//   - *gorm.DB: 打开的数据库。
+//   - error: 如果创建连接器、打开数据库或注册插件失败，则返回错误信息。
+func OpenDB(conf Config, opts ...gorm.Option) (*gorm.DB, error) {
+	// 建立 SSH 连接与打开数据库共享 Retry.Deadline 的总时长
+	ctx, cancel := conf.Retry.Context(context.Background())
+	defer cancel()
+
+	conf, err := conf.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 每次重试时重新创建连接器，gorm.Open 失败时会关闭连接器中的连接池
+	db, err := driver.OpenDB(ctx, conf.dialector, conf.pool(), conf.retry(), opts...)
+	if err != nil {
+		// 释放 prepare 获取的 SSH 连接引用
+		_ = closeTunnels(conf)
+		return nil, err
+	}
+
+	if err := conf.use(db); err != nil {
+		// 注册插件失败时关闭已经打开的主库连接池
+		if sqlDB, dberr := db.DB(); dberr == nil {
+			sqlDB.Close()
+		}
+		_ = closeTunnels(conf)
+		return nil, err
+	}
+
+	return db, nil
+}
+
+var _ driver.Opener = Config{}
+
+// Open 实现 driver.Opener 接口，等同于 OpenDB(c, opts...)。
+func (c Config) Open(opts ...gorm.Option) (*gorm.DB, error) {
+	return OpenDB(c, opts...)
+}
+
+// Close 实现 driver.Opener 接口，关闭 Open 打开的主库与只读副本的连接池，并释放各自占用的一次 SSH 连接引用。
+// 使用中的连接在查询结束后才会关闭，需要等待时使用 driver.Handle。
+//
+// 参数:
+//   - db: Open 或 OpenDB 以该配置打开的数据库。
+//
+// 返回值:
+//   - error: 如果关闭连接池或 SSH 连接失败，则返回错误信息。
+func (c Config) Close(db *gorm.DB) error {
+	pools, err := driver.ConnPools(db)
+	errs := []error{err}
+	for _, pool := range pools {
+		errs = append(errs, pool.Close())
+	}
+
+	return errors.Join(append(errs, closeTunnels(append([]Config{c}, c.Replicas...)...))...)
+}
+
+// use 注册配置中的查询指标与读写分离插件，读写分离插件注册失败时释放只读副本的 SSH 连接引用。
+func (c Config) use(db *gorm.DB) error {
+	if c.Metrics != nil {
+		if err := db.Use(c.Metrics); err != nil {
+			return err
+		}
+	}
+
+	return resolver.Use(db, c.Replicas, c.ReplicaPolicy, c.pool())
 }
 
 // Open 根据给定的 DSN (数据源名称) 打开一个 MySQL 数据库连接。
@@ -82,6 +275,50 @@ func Open(dsn string) gorm.Dialector {
 	return mysql.Open(dsn)
 }
 
+// dsnConfig 返回结构化的 DSN 配置。
+// 设置了 DSN 时解析 DSN，否则返回 DSNConfig，两者都未设置时返回 nil。
+//
+// 返回值:
+//   - *mysqld.Config: 结构化的 DSN 配置。
+//   - error: DSN 无法解析时返回包装了 ErrDSNParse 的错误。
+func (c Config) dsnConfig() (*mysqld.Config, error) {
+	if c.DSN == "" {
+		return c.DSNConfig, nil
+	}
+
+	dsnConf, err := mysqld.ParseDSN(c.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDSNParse, err)
+	}
+
+	return dsnConf, nil
+}
+
+// retry 返回打开数据库时使用的重试策略，未设置日志记录器时使用 SSH 配置中的日志记录器。
+func (c Config) retry() driver.Retry {
+	retry := c.Retry
+	if retry.Logger == nil && c.SSH != nil {
+		retry.Logger = c.SSH.Logger
+	}
+
+	return retry
+}
+
+// pool 返回配置中的连接池参数，使用 SSH 时未设置的生命周期使用隧道的默认值。
+func (c Config) pool() driver.Pool {
+	pool := driver.Pool{
+		MaxOpenConns:    c.MaxOpenConns,
+		MaxIdleConns:    c.MaxIdleConns,
+		ConnMaxLifetime: c.ConnMaxLifetime,
+		ConnMaxIdleTime: c.ConnMaxIdleTime,
+	}
+	if c.SSH != nil {
+		return pool.Tunneled()
+	}
+
+	return pool
+}
+
 // config 将自定义配置对象转换为 mysql 驱动的 Config 结构体。
 func (c Config) config() mysql.Config {
 	return mysql.Config{
@@ -1,11 +1,29 @@
 package mysql
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/cotton-go/pkg/driver"
 	"github.com/cotton-go/pkg/ssh"
+	mysqld "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
@@ -45,3 +63,208 @@ func TestMySQL(t *testing.T) {
 		return nil
 	})
 }
+
+func TestNewWithError(t *testing.T) {
+	_, err := NewWithError(Config{DSN: "root:casaos@tcp(127.0.0.1:3306"})
+	if !errors.Is(err, ErrDSNParse) {
+		t.Fatalf("got %v, want ErrDSNParse", err)
+	}
+
+	_, err = NewWithError(Config{
+		DSN: "root:casaos@tcp(127.0.0.1:3306)/demo",
+		SSH: &ssh.Config{Host: "127.0.0.1", Port: 1, User: "jun", Type: ssh.ConfigTypeByPrivateKey, PrivateKey: "invalid"},
+	})
+	if !errors.Is(err, ErrSSHAuth) {
+		t.Fatalf("got %v, want ErrSSHAuth", err)
+	}
+
+	// 无法连接时按重试策略重试，返回最后一次的错误
+	_, err = NewWithError(Config{
+		DSN:   "root:casaos@tcp(127.0.0.1:3306)/demo",
+		SSH:   &ssh.Config{Host: "127.0.0.1", Port: 1, User: "jun", Password: "p"},
+		Retry: driver.Retry{MaxAttempts: 2, InitialBackoff: time.Millisecond, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))},
+	})
+	if !errors.Is(err, ErrSSHDial) || !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Fatalf("got %v, want ErrSSHDial after 2 attempts", err)
+	}
+}
+
+func TestTunnelNet(t *testing.T) {
+	conf := Config{DSN: "root:p@tcp(x@tcp(127.0.0.1:3306)/demo?parseTime=true"}
+	dsnConf, err := conf.dsnConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dsnConf.Net = tunnelNet("bastion-22-0-jun", dsnConf.Net)
+	parsed, err := mysqld.ParseDSN(dsnConf.FormatDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Net != "bastion-22-0-jun" || parsed.Addr != "127.0.0.1:3306" || parsed.Passwd != "p@tcp(x" {
+		t.Fatalf("unexpected dsn %s", dsnConf.FormatDSN())
+	}
+
+	if got := tunnelNet("bastion-22-0-jun", "unix"); got != "bastion-22-0-jun-unix" {
+		t.Fatalf("got %s, want bastion-22-0-jun-unix", got)
+	}
+
+	_, err = NewWithError(Config{SSH: &ssh.Config{Host: "127.0.0.1"}})
+	if !errors.Is(err, ErrDSNParse) {
+		t.Fatalf("got %v, want ErrDSNParse", err)
+	}
+}
+
+func TestParseURL(t *testing.T) {
+	conf, err := ParseURL("mysql+ssh://dbuser:p%40ss@db:3306/app?parseTime=true&ssh_host=bastion&ssh_user=ops&ssh_key=/keys/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dsnConf := conf.DSNConfig
+	if dsnConf.User != "dbuser" || dsnConf.Passwd != "p@ss" || dsnConf.Addr != "db:3306" || dsnConf.DBName != "app" || !dsnConf.ParseTime {
+		t.Fatalf("unexpected dsn %s", conf.DSN)
+	}
+	if conf.SSH == nil || conf.SSH.Host != "bastion" || conf.SSH.User != "ops" ||
+		conf.SSH.Type != ssh.ConfigTypeByPrivateKeyPath || conf.SSH.PrivateKeyPath != "/keys/id" {
+		t.Fatalf("unexpected ssh config %+v", conf.SSH)
+	}
+
+	want := "mysql+ssh://dbuser:xxxxx@db:3306/app?parseTime=true&ssh_host=bastion&ssh_key=%2Fkeys%2Fid&ssh_user=ops"
+	if got := conf.String(); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	// 再次解析隐藏密码后的 URL，除密码外配置不变
+	again, err := ParseURL(conf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.String() != want || again.DSNConfig.Passwd != ssh.RedactedSecret {
+		t.Fatalf("round trip mismatch: %s", again.String())
+	}
+
+	conf, err = ParseURL("mysql://root@/app?socket=/run/mysqld/mysqld.sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.DSNConfig.Net != "unix" || conf.DSNConfig.Addr != "/run/mysqld/mysqld.sock" || conf.SSH != nil {
+		t.Fatalf("unexpected dsn %s", conf.DSN)
+	}
+
+	for _, rawURL := range []string{"postgres://db/app", "mysql+ssh://db/app", "mysql://db/app?ssh_user=ops"} {
+		if _, err := ParseURL(rawURL); !errors.Is(err, ErrDSNParse) {
+			t.Fatalf("%s: got %v, want ErrDSNParse", rawURL, err)
+		}
+	}
+}
+
+func TestPool(t *testing.T) {
+	pool := Config{MaxOpenConns: 10}.pool()
+	if pool.MaxOpenConns != 10 || pool.ConnMaxLifetime != 0 {
+		t.Fatalf("unexpected pool %+v", pool)
+	}
+
+	pool = Config{ConnMaxIdleTime: 30 * time.Second, SSH: &ssh.Config{Host: "bastion"}}.pool()
+	if pool.ConnMaxLifetime != driver.DefaultTunnelConnMaxLifetime || pool.ConnMaxIdleTime != 30*time.Second {
+		t.Fatalf("unexpected tunneled pool %+v", pool)
+	}
+}
+
+// selfSignedPEM 生成一个自签名证书及其私钥的 PEM 内容。
+func selfSignedPEM(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "db"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return string(certPEM), string(keyPEM)
+}
+
+func TestTLS(t *testing.T) {
+	certPEM, keyPEM := selfSignedPEM(t)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte(certPEM), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	dialector, err := NewWithError(Config{
+		DSN: "root:casaos@tcp(db:3306)/demo",
+		TLS: &TLS{CA: caFile, Cert: certPEM, Key: keyPEM},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dsn := dialector.(*mysql.Dialector).DSN
+	parsed, err := mysqld.ParseDSN(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(parsed.TLSConfig, "cotton-") || parsed.TLS == nil || parsed.TLS.RootCAs == nil ||
+		len(parsed.TLS.Certificates) != 1 || parsed.TLS.ServerName != "db" {
+		t.Fatalf("unexpected tls config in dsn %s", dsn)
+	}
+
+	_, err = NewWithError(Config{DSN: "root:casaos@tcp(db:3306)/demo", TLS: &TLS{Cert: certPEM}})
+	if !errors.Is(err, ErrTLSConfig) {
+		t.Fatalf("got %v, want ErrTLSConfig", err)
+	}
+
+	// 无效的证书在建立 SSH 连接之前就返回错误
+	sshConf := &ssh.Config{Host: "127.0.0.1", Port: 1, User: "jun", Password: "p"}
+	_, err = NewWithError(Config{DSN: "root:casaos@tcp(db:3306)/demo", TLS: &TLS{Cert: certPEM}, SSH: sshConf})
+	if !errors.Is(err, ErrTLSConfig) {
+		t.Fatalf("got %v, want ErrTLSConfig", err)
+	}
+
+	// SSH 连接失败时不会注册 TLS 配置
+	tlsConf := &TLS{ServerName: "ssh-failed", InsecureSkipVerify: true}
+	_, err = NewWithError(Config{DSN: "root:casaos@tcp(db:3306)/demo", TLS: tlsConf, SSH: sshConf})
+	if !errors.Is(err, ErrSSHDial) {
+		t.Fatalf("got %v, want ErrSSHDial", err)
+	}
+	if _, err := mysqld.ParseDSN("root@tcp(db:3306)/demo?tls=" + tlsConf.name()); err == nil {
+		t.Fatal("tls config must not be registered when ssh fails")
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	errRotating := errors.New("rotating")
+	calls := 0
+	provider := driver.CredentialProviderFunc(func(context.Context) (driver.Credentials, error) {
+		calls++
+		return driver.Credentials{}, errRotating
+	})
+
+	dialector, err := NewWithError(Config{DSN: "root@tcp(127.0.0.1:1)/demo", Credentials: provider})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 建立连接时会先向凭据提供者获取密码
+	db := dialector.(*mysql.Dialector).Conn.(*sql.DB)
+	defer db.Close()
+	if err := db.Ping(); !errors.Is(err, errRotating) || calls != 1 {
+		t.Fatalf("got %v after %d calls, want rotating error", err, calls)
+	}
+}
@@ -0,0 +1,82 @@
+package mysql
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/cotton-go/pkg/driver"
+	mysqld "github.com/go-sql-driver/mysql"
+)
+
+// TLS 定义 MySQL 连接的 TLS 配置，证书与私钥可以是文件路径，也可以是内联的 PEM 内容。
+// 通过 SSH 隧道连接时同样生效，TLS 在隧道内与数据库服务器直接协商。
+type TLS struct {
+	CA                 string `json:"ca,omitempty"`                 // CA 证书，为空时使用系统根证书。
+	Cert               string `json:"cert,omitempty"`               // 客户端证书，与 Key 同时设置时使用双向认证。
+	Key                string `json:"key,omitempty"`                // 客户端私钥。
+	ServerName         string `json:"serverName,omitempty"`         // 校验服务端证书时使用的主机名，默认为 DSN 中的主机地址。
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"` // 跳过服务端证书校验，默认为 false。
+}
+
+// config 根据配置创建 tls.Config，只读取证书，不会注册到驱动中。
+//
+// 返回值:
+//   - *tls.Config: 创建的 TLS 配置。
+//   - error: 如果读取证书失败，则返回包装了 ErrTLSConfig 的错误。
+func (t TLS) config() (*tls.Config, error) {
+	config := &tls.Config{ServerName: t.ServerName, InsecureSkipVerify: t.InsecureSkipVerify}
+
+	if t.CA != "" {
+		pool, err := driver.CertPool(t.CA)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrTLSConfig, err)
+		}
+		config.RootCAs = pool
+	}
+
+	if t.Cert != "" || t.Key != "" {
+		if t.Cert == "" || t.Key == "" {
+			return nil, fmt.Errorf("%w: both cert and key are required", ErrTLSConfig)
+		}
+
+		pair, err := driver.KeyPair(t.Cert, t.Key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrTLSConfig, err)
+		}
+		config.Certificates = []tls.Certificate{pair}
+	}
+
+	return config, nil
+}
+
+// register 通过 mysqld.RegisterTLSConfig 注册 config 创建的 TLS 配置。
+// 相同的配置使用相同的名称，重复注册时覆盖之前的配置。
+//
+// 参数:
+//   - config: config 方法创建的 TLS 配置。
+//
+// 返回值:
+//   - string: 注册的名称，用作 DSN 中 tls 参数的值。
+//   - error: 如果注册失败，则返回包装了 ErrTLSConfig 的错误。
+func (t TLS) register(config *tls.Config) (string, error) {
+	name := t.name()
+	if err := mysqld.RegisterTLSConfig(name, config); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrTLSConfig, err)
+	}
+
+	return name, nil
+}
+
+// name 返回注册 TLS 配置使用的名称，由配置内容的摘要生成。
+func (t TLS) name() string {
+	h := sha256.New()
+	for _, s := range []string{t.CA, t.Cert, t.Key, t.ServerName, strconv.FormatBool(t.InsecureSkipVerify)} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+
+	return "cotton-" + hex.EncodeToString(h.Sum(nil)[:8])
+}
@@ -0,0 +1,176 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/cotton-go/pkg/driver"
+	"github.com/cotton-go/pkg/ssh"
+	mysqld "github.com/go-sql-driver/mysql"
+)
+
+var (
+	// tunnels 记录本驱动从所有驱动共享的 SSH 连接注册表中获取的引用，相同配置的数据库共用一个 SSH 连接。
+	tunnels = ssh.DefaultRegistry.Group()
+
+	// mu 保护 registered，保证拨号函数的注册与注销不会交错执行。
+	mu sync.Mutex
+	// registered 记录已经注册了拨号函数的 SSH 连接键。
+	registered = make(map[string]struct{})
+)
+
+// connectTunnel 按重试策略调用 registerTunnel，SSH 认证失败时不会重试。
+// ctx 超过截止时间后不再开始新的尝试，正在进行的 SSH 连接不会被中断。
+//
+// 参数:
+//   - ctx: 上下文，通常由 Retry.Context 创建，与打开数据库共享总时长的截止时间。
+//   - conf: SSH 连接配置。
+//   - retry: 重试策略，未设置日志记录器时使用 SSH 配置中的日志记录器。
+//
+// 返回值:
+//   - string: SSH 连接的键。
+//   - error: 最后一次尝试的错误。
+func connectTunnel(ctx context.Context, conf ssh.Config, retry driver.Retry) (string, error) {
+	if retry.Logger == nil {
+		retry.Logger = conf.Logger
+	}
+
+	var key string
+	err := retry.Do(ctx, "ssh connect", func(ctx context.Context) error {
+		if err := ctx.Err(); err != nil {
+			return driver.Permanent(err)
+		}
+
+		var err error
+		key, err = registerTunnel(conf)
+		if errors.Is(err, ErrSSHAuth) {
+			return driver.Permanent(err)
+		}
+
+		return err
+	})
+
+	return key, err
+}
+
+// registerTunnel 获取 SSH 连接并为其注册拨号函数，相同配置只会注册一次。
+// 拨号函数每次拨号时按键查找当前的 SSH 连接，因此不会因为重复调用 New 而指向新的连接。
+//
+// 参数:
+//   - conf: SSH 连接配置。
+//
+// 返回值:
+//   - string: SSH 连接的键，通过 tunnelNet 得到替换 DSN 中网络类型的名称。
+//   - error: 如果建立 SSH 连接失败，则返回错误信息。
+func registerTunnel(conf ssh.Config) (string, error) {
+	// 建立 SSH 连接时不持有 mu，避免阻塞其他主机的连接。
+	// CloseTunnel 在 mu 中释放引用并注销拨号函数，因此这里在 mu 中检查注册状态时总能看到注销的结果。
+	if _, err := tunnels.Acquire(conf); err != nil {
+		return "", err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	key := conf.Key()
+	if _, ok := registered[key]; ok {
+		return key, nil
+	}
+
+	// 注册一个自定义的拨号函数，使用 SSH 连接来拨号，SSH 连接断开时自动重新连接。
+	mysqld.RegisterDialContext(key, func(ctx context.Context, addr string) (net.Conn, error) {
+		return tunnels.DialContext(ctx, key, "tcp", addr)
+	})
+	// 注册一个通过 SSH 连接访问远程 Unix 域套接字的拨号函数。
+	mysqld.RegisterDialContext(key+"-unix", func(ctx context.Context, addr string) (net.Conn, error) {
+		return tunnels.DialContext(ctx, key, "unix", addr)
+	})
+
+	registered[key] = struct{}{}
+	return key, nil
+}
+
+// isTunnelNet 判断 DSN 中的网络类型是否可以通过 SSH 隧道转发。
+func isTunnelNet(net string) bool {
+	switch net {
+	case "", "tcp", "tcp4", "tcp6", "unix":
+		return true
+	default:
+		return false
+	}
+}
+
+// tunnelNet 返回 DSN 中网络类型对应的已注册拨号函数名称。
+//
+// 参数:
+//   - key: SSH 连接的键。
+//   - net: DSN 中原始的网络类型。
+//
+// 返回值:
+//   - string: unix 返回 key-unix，其余返回 key。
+func tunnelNet(key, net string) string {
+	if net == "unix" {
+		return key + "-unix"
+	}
+
+	return key
+}
+
+// deregister 注销 SSH 连接键对应的拨号函数，调用方需要持有 mu。
+func deregister(key string) {
+	mysqld.DeregisterDialContext(key)
+	mysqld.DeregisterDialContext(key + "-unix")
+	delete(registered, key)
+}
+
+// CloseTunnel 释放 SSH 配置对应的连接的一次引用。
+// 每次以该配置调用 New 都会增加一次引用，本驱动的引用全部释放后注销拨号函数，所有驱动的引用全部释放后关闭 SSH 连接。
+//
+// 参数:
+//   - conf: 创建数据库连接时使用的 SSH 配置。
+//
+// 返回值:
+//   - error: 如果关闭 SSH 连接失败，则返回错误信息。
+func CloseTunnel(conf ssh.Config) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	key := conf.Key()
+	closed, err := tunnels.Release(key)
+	if closed {
+		deregister(key)
+	}
+
+	return err
+}
+
+// CloseTunnels 释放 MySQL 驱动获取的所有 SSH 连接引用并注销对应的拨号函数，通常在程序退出时调用。
+// 其他驱动仍在使用的 SSH 连接保持打开。
+//
+// 返回值:
+//   - error: 关闭过程中遇到的第一个错误。
+func CloseTunnels() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	keys, err := tunnels.Close()
+	for _, key := range keys {
+		deregister(key)
+	}
+
+	return err
+}
+
+// closeTunnels 释放每个配置的 SSH 连接的一次引用，未设置 SSH 的配置会被跳过。
+func closeTunnels(confs ...Config) error {
+	var errs []error
+	for _, conf := range confs {
+		if conf.SSH != nil {
+			errs = append(errs, CloseTunnel(*conf.SSH))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,134 @@
+package mysql
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/cotton-go/pkg/driver"
+	"github.com/cotton-go/pkg/ssh"
+	mysqld "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// Scheme 是 MySQL 连接 URL 的 scheme，通过 SSH 隧道连接时为 "mysql+ssh"。
+const Scheme = "mysql"
+
+// querySocket 是 URL 中表示 unix 套接字路径的查询参数，设置后忽略 URL 中的主机地址。
+const querySocket = "socket"
+
+func init() {
+	driver.Register(Scheme, func() driver.Config { return &Config{} })
+	driver.RegisterURL(Scheme, func(rawURL string) (driver.Config, error) {
+		return ParseURL(rawURL)
+	})
+}
+
+// ParseURL 将连接 URL 解析为 MySQL 配置。
+// URL 的格式为 mysql[+ssh]://user:password@host:port/dbname?param=value，
+// 其中 ssh_ 开头的参数解析为 SSH 配置，socket 参数表示 unix 套接字路径，
+// 其他参数与 DSN 中的参数相同，例如 parseTime=true、charset=utf8mb4。
+//
+// 参数:
+//   - rawURL: 连接 URL，例如 mysql+ssh://dbuser:pw@db:3306/app?ssh_host=bastion&ssh_user=ops&ssh_key=/keys/id。
+//
+// 返回值:
+//   - Config: 解析得到的配置，DSN 与 DSNConfig 均已设置。
+//   - error: 如果 URL 格式错误，则返回包装了 ErrDSNParse 的错误。
+func ParseURL(rawURL string) (Config, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Config{}, fmt.Errorf("%w: %w", ErrDSNParse, err)
+	}
+	if u.Scheme != Scheme && u.Scheme != Scheme+"+ssh" {
+		return Config{}, fmt.Errorf("%w: unexpected url scheme %q", ErrDSNParse, u.Scheme)
+	}
+
+	query := u.Query()
+	sshConf, err := ssh.ConfigFromQuery(query)
+	if err != nil {
+		return Config{}, fmt.Errorf("%w: %w", ErrDSNParse, err)
+	}
+	if sshConf == nil && u.Scheme != Scheme {
+		return Config{}, fmt.Errorf("%w: %s is required for scheme %q", ErrDSNParse, ssh.QueryHost, u.Scheme)
+	}
+
+	socket := query.Get(querySocket)
+	query.Del(querySocket)
+
+	// 剩余的参数交给驱动解析，得到带有默认值的结构化配置
+	dsnConf, err := mysqld.ParseDSN("/?" + query.Encode())
+	if err != nil {
+		return Config{}, fmt.Errorf("%w: %w", ErrDSNParse, err)
+	}
+
+	if u.User != nil {
+		dsnConf.User = u.User.Username()
+		dsnConf.Passwd, _ = u.User.Password()
+	}
+
+	switch {
+	case socket != "":
+		dsnConf.Net = "unix"
+		dsnConf.Addr = socket
+	case u.Host != "":
+		dsnConf.Net = "tcp"
+		dsnConf.Addr = u.Host
+		if u.Port() == "" {
+			dsnConf.Addr = net.JoinHostPort(u.Hostname(), "3306")
+		}
+	}
+	dsnConf.DBName = strings.TrimPrefix(u.Path, "/")
+
+	return Config{DSN: dsnConf.FormatDSN(), DSNConfig: dsnConf, SSH: sshConf}, nil
+}
+
+// String 返回配置对应的连接 URL，数据库与 SSH 的密码会被替换为 ssh.RedactedSecret。
+// 返回的 URL 可以再次通过 ParseURL 解析，除密码外得到相同的配置。
+// DSN 无法解析时只返回 scheme。
+func (c Config) String() string {
+	u := url.URL{Scheme: Scheme}
+	if c.SSH != nil {
+		u.Scheme = Scheme + "+ssh"
+	}
+
+	dsnConf, err := c.dsnConfig()
+	if err != nil || dsnConf == nil {
+		return u.Scheme + "://"
+	}
+
+	switch {
+	case dsnConf.Passwd != "":
+		u.User = url.UserPassword(dsnConf.User, ssh.RedactedSecret)
+	case dsnConf.User != "":
+		u.User = url.User(dsnConf.User)
+	}
+	u.Path = "/" + dsnConf.DBName
+
+	// 去掉地址与认证信息后，FormatDSN 生成的查询参数即为非默认的驱动参数
+	params := dsnConf.Clone()
+	params.User, params.Passwd, params.Net, params.Addr, params.DBName = "", "", "", "", ""
+	query := url.Values{}
+	if _, rawQuery, ok := strings.Cut(params.FormatDSN(), "?"); ok {
+		query, _ = url.ParseQuery(rawQuery)
+	}
+
+	if dsnConf.Net == "unix" {
+		query.Set(querySocket, dsnConf.Addr)
+	} else {
+		u.Host = dsnConf.Addr
+	}
+
+	if c.SSH != nil {
+		c.SSH.EncodeQuery(query, true)
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// Dialector 实现 driver.Config 接口，等同于 NewWithError(c)。
+func (c Config) Dialector() (gorm.Dialector, error) {
+	return NewWithError(c)
+}
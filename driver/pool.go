@@ -0,0 +1,134 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 通过 SSH 隧道连接时连接池的默认值。
+// SSH 连接断开后，隧道上的数据库连接不会立即报错，较短的生命周期可以让这些连接尽快被回收。
+const (
+	DefaultTunnelConnMaxLifetime = 5 * time.Minute // 连接的默认最长使用时间
+	DefaultTunnelConnMaxIdleTime = time.Minute     // 连接的默认最长空闲时间
+)
+
+// Pool 定义数据库连接池的参数，对应 sql.DB 的 SetMaxOpenConns 等方法。
+// 为 0 的字段保持 database/sql 的默认值不变。
+type Pool struct {
+	MaxOpenConns    int           `json:"maxOpenConns,omitempty"`    // 最大打开连接数，小于 0 时不限制。
+	MaxIdleConns    int           `json:"maxIdleConns,omitempty"`    // 最大空闲连接数，小于 0 时不保留空闲连接。
+	ConnMaxLifetime time.Duration `json:"connMaxLifetime,omitempty"` // 连接的最长使用时间，小于 0 时不限制。
+	ConnMaxIdleTime time.Duration `json:"connMaxIdleTime,omitempty"` // 连接的最长空闲时间，小于 0 时不限制。
+}
+
+// Tunneled 返回通过 SSH 隧道连接时使用的连接池参数，未设置的生命周期使用隧道的默认值。
+func (p Pool) Tunneled() Pool {
+	if p.ConnMaxLifetime == 0 {
+		p.ConnMaxLifetime = DefaultTunnelConnMaxLifetime
+	}
+	if p.ConnMaxIdleTime == 0 {
+		p.ConnMaxIdleTime = DefaultTunnelConnMaxIdleTime
+	}
+
+	return p
+}
+
+// Apply 将连接池参数应用到连接池上，不支持的参数会被忽略。
+//
+// 参数:
+//   - connPool: 连接池，通常为 *sql.DB。
+func (p Pool) Apply(connPool gorm.ConnPool) {
+	if db, ok := connPool.(interface{ SetMaxOpenConns(int) }); ok && p.MaxOpenConns != 0 {
+		db.SetMaxOpenConns(p.MaxOpenConns)
+	}
+	if db, ok := connPool.(interface{ SetMaxIdleConns(int) }); ok && p.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(p.MaxIdleConns)
+	}
+	if db, ok := connPool.(interface{ SetConnMaxLifetime(time.Duration) }); ok && p.ConnMaxLifetime != 0 {
+		db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	}
+	if db, ok := connPool.(interface{ SetConnMaxIdleTime(time.Duration) }); ok && p.ConnMaxIdleTime != 0 {
+		db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+	}
+}
+
+// OpenDB 使用连接器打开数据库，并设置连接池参数。
+// 打开数据库与首次 Ping 按重试策略重试，全部失败时关闭已打开的连接池并返回最后一次的错误。
+// gorm.Open 初始化失败时会关闭连接器中的连接池，因此每次重新打开时都通过 dialector 创建新的连接器。
+// 选项中设置了 DisableAutomaticPing 时不执行 Ping。
+//
+// 参数:
+//   - ctx: 上下文，通常由 Retry.Context 创建，与建立 SSH 连接共享总时长的截止时间。
+//   - dialector: 创建数据库连接器的函数，每次打开数据库时调用，返回的错误不会重试。
+//   - pool: 连接池参数。
+//   - retry: 打开数据库与首次 Ping 的重试策略。
+//   - opts: 传递给 gorm.Open 的选项。
+//
+// 返回值:
+//   - *gorm.DB: 打开的数据库。
+//   - error: 如果打开数据库失败，则返回错误信息。
+func OpenDB(ctx context.Context, dialector func() (gorm.Dialector, error), pool Pool, retry Retry, opts ...gorm.Option) (*gorm.DB, error) {
+	// 由 OpenDB 负责 Ping，以便 Ping 失败时只重试 Ping 而不重新打开连接池
+	ping := &deferPing{}
+	opts = append(opts, ping)
+
+	var (
+		db    *gorm.DB
+		sqlDB *sql.DB
+	)
+	err := retry.Do(ctx, "open database", func(ctx context.Context) error {
+		if db == nil {
+			d, err := dialector()
+			if err != nil {
+				return Permanent(err)
+			}
+
+			opened, err := gorm.Open(d, opts...)
+			if err != nil {
+				// 初始化失败时 gorm 已经关闭了连接池，下次尝试时重新创建连接器
+				return err
+			}
+
+			// 使用 gorm 的 Conn 时 ConnPool 不是 *sql.DB，通过 db.DB() 取得底层的连接池
+			if sqlDB, err = opened.DB(); err != nil {
+				return Permanent(err)
+			}
+			db = opened
+			pool.Apply(sqlDB)
+		}
+
+		if ping.disabled {
+			return nil
+		}
+
+		return sqlDB.PingContext(ctx)
+	})
+	if err != nil {
+		if sqlDB != nil {
+			sqlDB.Close()
+		}
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// deferPing 是一个 gorm.Option，它关闭 gorm.Open 中的自动 Ping，并记录调用方是否原本就关闭了 Ping。
+type deferPing struct {
+	disabled bool
+}
+
+// Apply 实现 gorm.Option 接口，gorm.Open 会在 *gorm.Config 之后应用它。
+func (p *deferPing) Apply(config *gorm.Config) error {
+	p.disabled = config.DisableAutomaticPing
+	config.DisableAutomaticPing = true
+	return nil
+}
+
+// AfterInitialize 实现 gorm.Option 接口。
+func (p *deferPing) AfterInitialize(*gorm.DB) error {
+	return nil
+}
@@ -0,0 +1,85 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// stubConnector 是一个无法建立连接的连接器，只用于检查连接池参数。
+type stubConnector struct{}
+
+func (stubConnector) Connect(context.Context) (sqldriver.Conn, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (stubConnector) Driver() sqldriver.Driver {
+	return nil
+}
+
+func TestPool(t *testing.T) {
+	pool := Pool{ConnMaxLifetime: -1}.Tunneled()
+	if pool.ConnMaxLifetime != -1 || pool.ConnMaxIdleTime != DefaultTunnelConnMaxIdleTime {
+		t.Fatalf("unexpected tunneled pool %+v", pool)
+	}
+
+	db := sql.OpenDB(stubConnector{})
+	defer db.Close()
+
+	Pool{MaxOpenConns: 3, ConnMaxIdleTime: time.Minute}.Apply(db)
+	if got := db.Stats().MaxOpenConnections; got != 3 {
+		t.Fatalf("got max open conns %d, want 3", got)
+	}
+}
+
+// flakyDialector 是使用给定连接池的 dryRunDialector，fail 为 true 时初始化失败。
+type flakyDialector struct {
+	dryRunDialector
+	conn *sql.DB
+	fail bool
+}
+
+func (d flakyDialector) Initialize(db *gorm.DB) error {
+	d.dryRunDialector.Initialize(db)
+	db.ConnPool = d.conn
+	if d.fail {
+		return errors.New("server has gone away")
+	}
+
+	return nil
+}
+
+func TestOpenDBRetry(t *testing.T) {
+	var conns []*sql.DB
+	dialector := func() (gorm.Dialector, error) {
+		conn := sql.OpenDB(nopConnector{})
+		conns = append(conns, conn)
+		return flakyDialector{conn: conn, fail: len(conns) == 1}, nil
+	}
+
+	retry := Retry{MaxAttempts: 2, InitialBackoff: time.Millisecond, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	db, err := OpenDB(context.Background(), dialector, Pool{MaxOpenConns: 2}, retry, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer conns[1].Close()
+
+	// gorm 关闭了第一次失败时的连接池，重试时使用新的连接池
+	if len(conns) != 2 {
+		t.Fatalf("dialector created %d times, want 2", len(conns))
+	}
+	if err := conns[0].Ping(); err == nil {
+		t.Fatal("failed connection pool was not closed")
+	}
+	if sqlDB, err := db.DB(); err != nil || sqlDB != conns[1] || sqlDB.Stats().MaxOpenConnections != 2 {
+		t.Fatalf("unexpected connection pool %v, %v", sqlDB, err)
+	}
+}
@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+	"fmt"
+
+	"github.com/cotton-go/pkg/driver"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/lib/pq"
+)
+
+// credentialConnector 是一个 lib/pq 的连接器，每次建立新连接时从凭据提供者获取用户名与密码。
+type credentialConnector struct {
+	dsn      string
+	dialer   *Dialector
+	provider driver.CredentialProvider
+}
+
+// Connect 实现 sqldriver.Connector 接口。
+func (c *credentialConnector) Connect(ctx context.Context) (sqldriver.Conn, error) {
+	creds, err := c.provider.Credentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get postgres credentials: %w", err)
+	}
+
+	params := map[string]string{"password": creds.Password}
+	if creds.User != "" {
+		params["user"] = creds.User
+	}
+
+	dsn, err := setDSNParams(c.dsn, params)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDSNParse, err)
+	}
+
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDSNParse, err)
+	}
+	connector.Dialer(c.dialer)
+
+	return connector.Connect(ctx)
+}
+
+// Driver 实现 sqldriver.Connector 接口。
+func (c *credentialConnector) Driver() sqldriver.Driver {
+	return c.dialer
+}
+
+// beforeConnect 返回一个 pgx 的连接选项，每次建立新连接时从凭据提供者获取用户名与密码。
+func beforeConnect(provider driver.CredentialProvider) stdlib.OptionOpenDB {
+	return stdlib.OptionBeforeConnect(func(ctx context.Context, config *pgx.ConnConfig) error {
+		creds, err := provider.Credentials(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to get postgres credentials: %w", err)
+		}
+
+		if creds.User != "" {
+			config.User = creds.User
+		}
+		config.Password = creds.Password
+		return nil
+	})
+}
@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/cotton-go/pkg/driver"
+	"gorm.io/driver/postgres"
+)
+
+func TestCredentials(t *testing.T) {
+	errRotating := errors.New("rotating")
+	var users []string
+	provider := driver.CredentialProviderFunc(func(context.Context) (driver.Credentials, error) {
+		users = append(users, "app")
+		return driver.Credentials{User: "app"}, errRotating
+	})
+
+	dialector, err := NewWithError(Config{DSN: "host=127.0.0.1 port=1 sslmode=disable", Credentials: provider})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pgx 建立连接前会先向凭据提供者获取密码
+	db := dialector.(*postgres.Dialector).Conn.(*sql.DB)
+	defer db.Close()
+	if err := db.Ping(); !errors.Is(err, errRotating) || len(users) != 1 {
+		t.Fatalf("got %v after %d calls, want rotating error", err, len(users))
+	}
+
+	// lib/pq 的连接器同样在每次建立连接前获取密码
+	connector := &credentialConnector{dsn: "host=db sslmode=disable", dialer: &Dialector{key: "missing"}, provider: provider}
+	if _, err := connector.Connect(context.Background()); !errors.Is(err, errRotating) || len(users) != 2 {
+		t.Fatalf("got %v after %d calls, want rotating error", err, len(users))
+	}
+}
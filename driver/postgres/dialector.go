@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"context"
 	"database/sql/driver"
 	"net"
 	"time"
@@ -13,6 +14,7 @@ import (
 // 它用于后续的数据库操作，通过 SSH 隧道进行。
 type Dialector struct {
 	conn *ssh.Client // conn 字段存储了一个指向 ssh.Client 的指针，
+	key  string      // key 非空时，每次拨号从共享的 SSH 连接中按键查找当前连接
 }
 
 // NewDialector 创建一个新的 Dialector 实例。
@@ -23,7 +25,7 @@ type Dialector struct {
 // 返回值:
 //   - *Dialector 类型的指针，用于后续的 SSH 操作。
 func NewDialector(conn *ssh.Client) *Dialector {
-	return &Dialector{conn}
+	return &Dialector{conn: conn}
 }
 
 // Open 打开一个数据库连接。
@@ -41,17 +43,28 @@ func (d *Dialector) Open(dsn string) (_ driver.Conn, err error) {
 }
 
 // Dial 建立到指定网络地址的连接。
-// 该方法利用 d.conn 的 Client 方法获取的客户端进行连接操作。
+// 该方法利用 d.conn 通过 SSH 隧道进行连接操作。
+// 当 DSN 中的 host 为套接字目录（例如 /var/run/postgresql）时，pq 会以 "unix" 网络类型拨号，
+// 此时通过 SSH 连接转发到远程主机上的 Unix 域套接字。
 //
 // 参数:
-//   - network: 网络类型，例如 "tcp"、"udp" 等。
+//   - network: 网络类型，例如 "tcp"、"unix" 等。
 //   - address: 要连接的网络地址。
 //
 // 返回值:
 //   - net.Conn: 建立的网络连接。
 //   - error: 如果连接失败，则返回错误信息。
 func (d *Dialector) Dial(network, address string) (net.Conn, error) {
-	return d.conn.Client().Dial(network, address)
+	if d.key != "" {
+		// 通过 New 注册的 Dialector 使用共享的 SSH 连接，连接断开时自动重新连接。
+		return tunnels.DialContext(context.Background(), d.key, network, address)
+	}
+
+	if network == "unix" {
+		return d.conn.DialUnix(address)
+	}
+
+	return d.conn.Dial(network, address)
 }
 
 // DialTimeout 在指定超时时间内，通过特定的网络和地址进行连接。
@@ -67,5 +80,5 @@ func (d *Dialector) Dial(network, address string) (net.Conn, error) {
 //   - net.Conn: 建立的连接对象。
 //   - error: 如果连接失败，会返回一个错误。
 func (d *Dialector) DialTimeout(network, address string, _ time.Duration) (net.Conn, error) {
-	return d.conn.Client().Dial(network, address)
+	return d.Dial(network, address)
 }
@@ -1,18 +1,26 @@
 module github.com/cotton-go/pkg/driver/postgres
 
-go 1.17
+go 1.21
 
 require (
 	github.com/cotton-go/pkg/ssh v0.0.0-20240816034421-034ecfc24f77
+	github.com/jackc/pgx/v5 v5.5.5
 	github.com/lib/pq v1.10.9
 	gorm.io/driver/postgres v1.5.9
 	gorm.io/gorm v1.25.11
 )
 
 require (
+	github.com/kr/text v0.2.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+	gorm.io/plugin/dbresolver v1.5.2 // indirect
+)
+
+require (
+	github.com/cotton-go/pkg/driver v0.0.0
+	github.com/cotton-go/pkg/driver/resolver v0.0.0
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a // indirect
-	github.com/jackc/pgx/v5 v5.5.5 // indirect
 	github.com/jackc/puddle/v2 v2.2.1 // indirect
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
@@ -22,3 +30,9 @@ require (
 	golang.org/x/sys v0.23.0 // indirect
 	golang.org/x/text v0.17.0 // indirect
 )
+
+replace github.com/cotton-go/pkg/ssh => ../../ssh
+
+replace github.com/cotton-go/pkg/driver/resolver => ../resolver
+
+replace github.com/cotton-go/pkg/driver => ../
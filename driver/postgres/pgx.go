@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Backend 定义了通过 SSH 隧道连接 PostgreSQL 时使用的底层驱动。
+// 未设置 SSH 时 gorm 始终使用 pgx，该选项不生效。
+type Backend uint8
+
+const (
+	// BackendPQ 表示使用 lib/pq 驱动，通过 pq.Dialer 转发连接，为默认值。
+	BackendPQ Backend = iota
+	// BackendPGX 表示使用 pgx v5 驱动，通过 pgconn.Config 的 DialFunc 转发连接，
+	// 支持二进制协议、COPY 以及基于 context 的取消。
+	BackendPGX
+)
+
+// openPGX 使用 pgx 打开一个数据库连接池，key 非空时通过 SSH 隧道连接。
+//
+// 参数:
+//   - key: 共享 SSH 连接的键，每次拨号时按键查找当前的 SSH 连接，为空时直接连接。
+//   - conf: 数据库配置，使用其中的 DSN、PreferSimpleProtocol、证书与凭据提供者。
+//
+// 返回值:
+//   - *sql.DB: 数据库连接池。
+//   - error: DSN 无法解析时返回包装了 ErrDSNParse 的错误，证书无效时返回包装了 ErrTLSConfig 的错误。
+func openPGX(key string, conf Config) (*sql.DB, error) {
+	config, err := pgx.ParseConfig(conf.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDSNParse, err)
+	}
+
+	if conf.PreferSimpleProtocol {
+		config.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+	}
+
+	if err := conf.applyTLS(&config.Config); err != nil {
+		return nil, err
+	}
+
+	var opts []stdlib.OptionOpenDB
+	if conf.Credentials != nil {
+		opts = append(opts, beforeConnect(conf.Credentials))
+	}
+
+	if key == "" {
+		return stdlib.OpenDB(*config, opts...), nil
+	}
+
+	// 主机名由 SSH 服务器解析，本地不做 DNS 查询，避免内网域名在本地无法解析。
+	config.LookupFunc = func(_ context.Context, host string) ([]string, error) {
+		return []string{host}, nil
+	}
+
+	// 通过 SSH 连接拨号，host 为套接字目录时 pgx 会以 unix 网络类型拨号，SSH 连接断开时自动重新连接。
+	config.DialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return tunnels.DialContext(ctx, key, network, addr)
+	}
+
+	return stdlib.OpenDB(*config, opts...), nil
+}
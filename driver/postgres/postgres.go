@@ -1,10 +1,17 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/cotton-go/pkg/driver"
+	"github.com/cotton-go/pkg/driver/resolver"
 	"github.com/cotton-go/pkg/ssh"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -13,35 +20,87 @@ import (
 type Config struct {
 	// DriverName 是用于连接 PostgreSQL 数据库的驱动程序的名称。
 	// 例如 "postgres"。
-	DriverName string
+	DriverName string `json:"driverName"`
 
 	// DSN 是用于连接 PostgreSQL 数据库的数据源名称。
 	// 它包含了连接数据库所需的信息，如数据库地址、用户名和密码等。
-	DSN string
+	DSN string `json:"dsn,omitempty"`
 
 	// WithoutQuotingCheck 表示是否禁用字段名称的引号检查。
 	// 如果设置为 true，则不会对字段名称进行引号检查，否则会对字段名称进行引号检查。
-	WithoutQuotingCheck bool
+	WithoutQuotingCheck bool `json:"withoutQuotingCheck"`
 
 	// PreferSimpleProtocol 表示是否偏好使用简单协议。
 	// 如果设置为 true，则偏好使用简单协议来连接 PostgreSQL 数据库，否则偏好使用复杂协议。
-	PreferSimpleProtocol bool
+	PreferSimpleProtocol bool `json:"preferSimpleProtocol"`
 
 	// WithoutReturning 表示是否禁用 RETURNING 子句。
 	// 如果设置为 true，则不会在 SQL 语句中使用 RETURNING 子句，否则会使用 RETURNING 子句。
-	WithoutReturning bool
+	WithoutReturning bool `json:"withoutReturning"`
 
 	// Conn 是已经存在的数据库连接池。
 	// 如果设置了该值，则会使用该连接池建立数据库连接。
-	Conn gorm.ConnPool
+	Conn gorm.ConnPool `json:"connPool,omitempty"`
 
 	// SSH 是用于通过 SSH 连接 PostgreSQL 数据库的 SSH 配置。
 	// 如果设置了该值，则会通过 SSH 隧道建立数据库连接。
-	SSH *ssh.Config
+	SSH *ssh.Config `json:"ssh,omitempty"`
+
+	// Backend 是通过 SSH 隧道连接时使用的底层驱动，默认为 BackendPQ。
+	// 设置为 BackendPGX 时使用 pgx v5，此时 DriverName 不生效。
+	Backend Backend `json:"backend,omitempty"`
+
+	// Replicas 是只读副本的配置，每个副本可以使用独立的 SSH 配置。
+	// 通过 NewResolver 创建读写分离插件后，查询语句会路由到这些副本。
+	Replicas []Config `json:"replicas,omitempty"`
+
+	// ReplicaPolicy 是选择只读副本的策略，默认为随机。
+	ReplicaPolicy resolver.Policy `json:"replicaPolicy,omitempty"`
+
+	// MaxOpenConns 是连接池的最大打开连接数，默认为 0，即不限制。
+	MaxOpenConns int `json:"maxOpenConns,omitempty"`
+
+	// MaxIdleConns 是连接池的最大空闲连接数。
+	// 默认为 0，即使用 database/sql 的默认值 2，小于 0 时不保留空闲连接。
+	MaxIdleConns int `json:"maxIdleConns,omitempty"`
+
+	// ConnMaxLifetime 是连接的最长使用时间，小于 0 时不限制。
+	// 默认为 0，即不限制，使用 SSH 时默认为 5 分钟，以便回收断开的 SSH 通道上的连接。
+	ConnMaxLifetime time.Duration `json:"connMaxLifetime,omitempty"`
+
+	// ConnMaxIdleTime 是连接的最长空闲时间，小于 0 时不限制。
+	// 默认为 0，即不限制，使用 SSH 时默认为 1 分钟。
+	ConnMaxIdleTime time.Duration `json:"connMaxIdleTime,omitempty"`
+
+	// SSLMode 是连接使用的 TLS 模式，对应 libpq 的 sslmode，例如 "require"、"verify-ca"、"verify-full"。
+	// 设置后覆盖 DSN 中的 sslmode，通过 SSH 隧道连接时同样生效。
+	SSLMode string `json:"sslMode,omitempty"`
+
+	// SSLRootCert 是校验服务端证书的 CA 证书，可以是文件路径或内联的 PEM 内容。
+	// 设置后 sslmode 为 require 时等同于 verify-ca。
+	SSLRootCert string `json:"sslRootCert,omitempty"`
+
+	// SSLCert 是客户端证书，可以是文件路径或内联的 PEM 内容，需要与 SSLKey 同时设置。
+	SSLCert string `json:"sslCert,omitempty"`
+
+	// SSLKey 是客户端私钥，可以是文件路径或内联的 PEM 内容。
+	SSLKey string `json:"sslKey,omitempty"`
+
+	// Credentials 是凭据提供者，每次建立新连接时获取用户名与密码，用于密码定期轮换的场景。
+	// 设置后 DSN 中的密码不再使用。
+	Credentials driver.CredentialProvider `json:"-"`
+
+	// Retry 是启动时建立 SSH 连接与首次 Ping 的重试策略，默认不重试。
+	// New 与 NewWithError 只重试 SSH 连接，OpenDB 同时重试打开数据库与首次 Ping。
+	Retry driver.Retry `json:"retry,omitempty"`
+
+	// Metrics 是查询指标插件，OpenDB 时注册到返回的 gorm.DB 上，默认为 nil。
+	Metrics *driver.Metrics `json:"-"`
 }
 
 // New 根据提供的配置创建一个新的 Gorm 数据库连接。
 // 它支持通过 SSH 隧道进行连接，如果配置中提供了 SSH 信息。
+// 如果 DSN 无法解析或 SSH 连接失败，New 会直接 panic，需要处理错误时请使用 NewWithError。
 //
 // 参数:
 //   - conf: 数据库和 SSH 配置。
@@ -49,25 +108,236 @@ type Config struct {
 // 返回值:
 //   - gorm.Dialector: 用于 Gorm 以建立数据库连接的接口。
 func New(conf Config) gorm.Dialector {
-	// 检查是否提供了 SSH 配置，如果提供了，则尝试通过 SSH 进行连接。
-	if sshConf := conf.SSH; sshConf != nil {
-		// 使用 SSH 配置尝试建立连接。
-		conn, err := ssh.Connect(*sshConf)
+	dialector, err := NewWithError(conf)
+	if err != nil {
+		// 如果创建失败，抛出异常，避免返回 nil 导致 gorm.Open 时空指针崩溃。
+		panic(err)
+	}
+
+	return dialector
+}
+
+// NewWithError 与 New 相同，但在失败时返回错误而不是 panic。
+//
+// 参数:
+//   - conf: 数据库和 SSH 配置。
+//
+// 返回值:
+//   - gorm.Dialector: 用于 Gorm 以建立数据库连接的接口。
+//   - error: 创建失败时返回的错误，可以通过 errors.Is 与 ErrSSHDial、ErrSSHAuth、ErrDSNParse、ErrTLSConfig 比较。
+func NewWithError(conf Config) (gorm.Dialector, error) {
+	ctx, cancel := conf.Retry.Context(context.Background())
+	defer cancel()
+
+	conf, key, err := conf.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dialector, err := conf.dialector(key)
+	if err != nil {
+		// 释放本次获取的 SSH 连接引用。
+		_ = closeTunnels(conf)
+		return nil, err
+	}
+
+	return dialector, nil
+}
+
+// prepare 校验 DSN，将 TLS 配置写入 DSN 并获取共享的 SSH 连接。
+// 设置了 SSH 时返回 SSH 连接的键，并占用一次 SSH 连接引用，需要通过 closeTunnels 释放，ctx 限制建立 SSH 连接的总时长。
+func (c Config) prepare(ctx context.Context) (Config, string, error) {
+	// 提前校验 DSN，避免在 gorm.Open 时才发现格式错误。
+	if err := c.parseDSN(); err != nil {
+		return c, "", err
+	}
+
+	// 将 TLS 配置写入 DSN。
+	c, err := c.withSSL()
+	if err != nil {
+		return c, "", err
+	}
+
+	// 检查是否提供了 SSH 配置，如果提供了，则获取共享的 SSH 连接。
+	if sshConf := c.SSH; sshConf != nil {
+		// 获取 SSH 连接并注册 SQL 驱动，相同配置只建立一次连接、只注册一次驱动。
+		key, err := connectTunnel(ctx, *sshConf, c.Retry)
+		if err != nil {
+			// 如果连接失败，返回错误，错误类型为 ErrSSHDial 或 ErrSSHAuth。
+			return c, "", err
+		}
+
+		return c, key, nil
+	}
+
+	return c, "", nil
+}
+
+// dialector 基于 prepare 返回的配置创建 PostgreSQL 数据库连接器。
+// 需要由驱动打开连接池时每次调用都会打开新的连接池，因此可以在 gorm.Open 失败后重新调用。
+//
+// 参数:
+//   - key: prepare 返回的 SSH 连接的键，未使用 SSH 时为空。
+func (c Config) dialector(key string) (gorm.Dialector, error) {
+	if key != "" {
+		switch c.Backend {
+		case BackendPGX:
+			// 使用 pgx 打开连接池，并交由 Gorm 直接使用。
+			db, err := openPGX(key, c)
+			if err != nil {
+				return nil, err
+			}
+
+			c.Conn = db
+		default:
+			if c.Credentials != nil {
+				// 每次建立新连接时获取用户名与密码，并通过 SSH 连接拨号。
+				c.Conn = sql.OpenDB(&credentialConnector{dsn: c.DSN, dialer: &Dialector{key: key}, provider: c.Credentials})
+				break
+			}
+
+			// 更新配置中的驱动名，以便 Gorm 可以使用通过 SSH 建立的连接。
+			c.DriverName = key
+		}
+	} else if (c.hasCerts() || c.Credentials != nil) && c.Conn == nil {
+		// Gorm 通过 DSN 打开连接时无法设置证书与凭据提供者，改为使用 pgx 打开连接池。
+		db, err := openPGX("", c)
 		if err != nil {
-			// 如果连接失败，返回 nil，表示无法建立数据库连接。
-			return nil
+			return nil, err
 		}
 
-		// 根据 SSH 配置生成一个唯一的键，用于注册新的 SQL 驱动名。
-		key := fmt.Sprintf("%s-%d-%d-%s", sshConf.Host, sshConf.Port, sshConf.Type, sshConf.User)
-		// 使用建立的 SSH 连接注册新的 SQL 驱动。
-		sql.Register(key, NewDialector(conn))
-		// 更新配置中的驱动名，以便 Gorm 可以使用通过 SSH 建立的连接。
-		conf.DriverName = key
+		c.Conn = db
 	}
 
 	// 使用更新后的配置创建并返回一个新的 PostgreSQL 数据库连接。
-	return postgres.New(conf.config())
+	return postgres.New(c.config()), nil
+}
+
+// OpenDB 根据配置打开数据库，并按配置中的 MaxOpenConns 等参数设置连接池。
+// 配置了 Replicas 时同时注册读写分离插件，只读副本使用相同的连接池参数。
+// 配置了 Metrics 时同时注册查询指标插件。
+// 关闭返回的数据库不会释放 SSH 隧道，需要同时释放时使用 driver.OpenHandle(conf) 或 Config.Close。
+//
+// 参数:
+//   - conf: 数据库和 SSH 配置。
+//   - opts: 传递给 gorm.Open 的选项。
+//
+// 返回值:
+//   - *gorm.DB: 打开的数据库。
+//   - error: 如果创建连接器、打开数据库或注册插件失败，则返回错误信息。
+func OpenDB(conf Config, opts ...gorm.Option) (*gorm.DB, error) {
+	// 建立 SSH 连接与打开数据库共享 Retry.Deadline 的总时长
+	ctx, cancel := conf.Retry.Context(context.Background())
+	defer cancel()
+
+	conf, key, err := conf.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 每次重试时重新创建连接器，gorm.Open 失败时会关闭连接器中的连接池
+	dialector := func() (gorm.Dialector, error) { return conf.dialector(key) }
+	db, err := driver.OpenDB(ctx, dialector, conf.pool(), conf.retry(), opts...)
+	if err != nil {
+		// 释放 prepare 获取的 SSH 连接引用
+		_ = closeTunnels(conf)
+		return nil, err
+	}
+
+	if err := conf.use(db); err != nil {
+		// 注册插件失败时关闭已经打开的主库连接池
+		if sqlDB, dberr := db.DB(); dberr == nil {
+			sqlDB.Close()
+		}
+		_ = closeTunnels(conf)
+		return nil, err
+	}
+
+	return db, nil
+}
+
+var _ driver.Opener = Config{}
+
+// Open 实现 driver.Opener 接口，等同于 OpenDB(c, opts...)。
+func (c Config) Open(opts ...gorm.Option) (*gorm.DB, error) {
+	return OpenDB(c, opts...)
+}
+
+// Close 实现 driver.Opener 接口，关闭 Open 打开的主库与只读副本的连接池，并释放各自占用的一次 SSH 连接引用。
+// 使用中的连接在查询结束后才会关闭，需要等待时使用 driver.Handle。
+//
+// 参数:
+//   - db: Open 或 OpenDB 以该配置打开的数据库。
+//
+// 返回值:
+//   - error: 如果关闭连接池或 SSH 连接失败，则返回错误信息。
+func (c Config) Close(db *gorm.DB) error {
+	pools, err := driver.ConnPools(db)
+	errs := []error{err}
+	for _, pool := range pools {
+		errs = append(errs, pool.Close())
+	}
+
+	return errors.Join(append(errs, closeTunnels(append([]Config{c}, c.Replicas...)...))...)
+}
+
+// use 注册配置中的查询指标与读写分离插件，读写分离插件注册失败时释放只读副本的 SSH 连接引用。
+func (c Config) use(db *gorm.DB) error {
+	if c.Metrics != nil {
+		if err := db.Use(c.Metrics); err != nil {
+			return err
+		}
+	}
+
+	return resolver.Use(db, c.Replicas, c.ReplicaPolicy, c.pool())
+}
+
+// parseDSN 使用实际建立连接的驱动校验 DSN 的格式。
+// 通过 SSH 隧道且使用 BackendPQ 连接时使用 lib/pq，否则使用 pgx。
+//
+// 返回值:
+//   - error: DSN 无法解析时返回包装了 ErrDSNParse 的错误。
+func (c Config) parseDSN() error {
+	if c.DSN == "" {
+		return nil
+	}
+
+	var err error
+	if c.SSH != nil && c.Backend == BackendPQ {
+		_, err = pq.NewConnector(c.DSN)
+	} else {
+		_, err = pgconn.ParseConfig(c.DSN)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDSNParse, err)
+	}
+
+	return nil
+}
+
+// retry 返回打开数据库时使用的重试策略，未设置日志记录器时使用 SSH 配置中的日志记录器。
+func (c Config) retry() driver.Retry {
+	retry := c.Retry
+	if retry.Logger == nil && c.SSH != nil {
+		retry.Logger = c.SSH.Logger
+	}
+
+	return retry
+}
+
+// pool 返回配置中的连接池参数，使用 SSH 时未设置的生命周期使用隧道的默认值。
+func (c Config) pool() driver.Pool {
+	pool := driver.Pool{
+		MaxOpenConns:    c.MaxOpenConns,
+		MaxIdleConns:    c.MaxIdleConns,
+		ConnMaxLifetime: c.ConnMaxLifetime,
+		ConnMaxIdleTime: c.ConnMaxIdleTime,
+	}
+	if c.SSH != nil {
+		return pool.Tunneled()
+	}
+
+	return pool
 }
 
 // config 将当前配置对象转换为 postgres.Config 类型的配置。
@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"github.com/cotton-go/pkg/driver/resolver"
+	"gorm.io/gorm"
+)
+
+// NewResolver 根据配置中的 Replicas 创建读写分离插件。
+// 查询语句按 ReplicaPolicy 路由到只读副本，写入语句与事务仍然使用 gorm.Open 时的主库连接。
+// 每个副本都是完整的配置，设置了 SSH 时与其他连接共享同一主机的 SSH 隧道。
+// 副本的连接池参数取自主库的配置，只应用到副本上，不会覆盖主库的连接池；任意副本使用 SSH 时，未设置的生命周期使用隧道的默认值。
+//
+// 参数:
+//   - conf: 主库的配置，只使用其中的 Replicas、ReplicaPolicy 与连接池参数。
+//
+// 返回值:
+//   - gorm.Plugin: 通过 db.Use 注册的插件。
+//   - error: 副本的 DSN 无法解析、SSH 连接失败或策略无法识别时返回错误。
+func NewResolver(conf Config) (gorm.Plugin, error) {
+	return resolver.NewFromConfigs(conf.Replicas, conf.ReplicaPolicy, conf.pool())
+}
+
+// Tunneled 返回配置是否通过 SSH 隧道连接，实现 resolver.Replica 接口。
+func (c Config) Tunneled() bool {
+	return c.SSH != nil
+}
+
+// ReleaseTunnel 释放以该配置创建连接器时获取的 SSH 连接引用，实现 resolver.Replica 接口。
+func (c Config) ReleaseTunnel() error {
+	return closeTunnels(c)
+}
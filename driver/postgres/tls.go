@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/cotton-go/pkg/driver"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// hasCerts 判断是否配置了 CA 证书或客户端证书。
+func (c Config) hasCerts() bool {
+	return c.SSLRootCert != "" || c.SSLCert != "" || c.SSLKey != ""
+}
+
+// sslMode 返回实际使用的 sslmode。
+// 与 libpq 一致，设置了 CA 证书时 require 等同于 verify-ca，这里显式转换，避免驱动因证书不是文件而忽略它。
+func (c Config) sslMode() string {
+	if c.SSLMode == "require" && c.SSLRootCert != "" {
+		return "verify-ca"
+	}
+
+	return c.SSLMode
+}
+
+// withSSL 将 TLS 相关的字段写入 DSN，覆盖 DSN 中的同名参数。
+// lib/pq 只能从 DSN 读取证书，客户端证书以 sslinline 的方式内联写入；
+// pgx 的证书在 openPGX 中直接设置到 tls.Config 上，DSN 中只写入 sslmode。
+//
+// 返回值:
+//   - Config: 更新了 DSN 的配置。
+//   - error: 如果证书无法读取或当前驱动不支持，则返回包装了 ErrTLSConfig 的错误。
+func (c Config) withSSL() (Config, error) {
+	if c.SSLMode == "" && !c.hasCerts() {
+		return c, nil
+	}
+
+	if (c.SSLCert == "") != (c.SSLKey == "") {
+		return c, fmt.Errorf("%w: both SSLCert and SSLKey are required", ErrTLSConfig)
+	}
+
+	params := make(map[string]string)
+	if mode := c.sslMode(); mode != "" {
+		params["sslmode"] = mode
+	}
+
+	if c.SSH != nil && c.Backend == BackendPQ {
+		switch {
+		case c.SSLCert != "":
+			// 内联模式下 lib/pq 要求同时提供客户端证书与私钥，CA 证书也需要内联
+			params["sslinline"] = "true"
+			for key, value := range map[string]string{"sslrootcert": c.SSLRootCert, "sslcert": c.SSLCert, "sslkey": c.SSLKey} {
+				if value == "" {
+					continue
+				}
+
+				data, err := driver.ReadPEM(value)
+				if err != nil {
+					return c, fmt.Errorf("%w: %w", ErrTLSConfig, err)
+				}
+				params[key] = string(data)
+			}
+		case c.SSLRootCert != "":
+			if isInlinePEM(c.SSLRootCert) {
+				return c, fmt.Errorf("%w: inline SSLRootCert requires a client certificate with lib/pq, use a file path or BackendPGX", ErrTLSConfig)
+			}
+			params["sslrootcert"] = c.SSLRootCert
+		}
+	}
+
+	dsn, err := setDSNParams(c.DSN, params)
+	if err != nil {
+		return c, fmt.Errorf("%w: %w", ErrDSNParse, err)
+	}
+	c.DSN = dsn
+
+	return c, nil
+}
+
+// applyTLS 将配置中的证书设置到 pgx 解析得到的所有 tls.Config 上，包括 sslmode 为 prefer 等模式时的备用配置。
+//
+// 参数:
+//   - config: pgx 解析 DSN 得到的连接配置。
+//
+// 返回值:
+//   - error: 如果证书无法读取或解析，则返回包装了 ErrTLSConfig 的错误。
+func (c Config) applyTLS(config *pgconn.Config) error {
+	if !c.hasCerts() {
+		return nil
+	}
+
+	var (
+		roots *x509.CertPool
+		certs []tls.Certificate
+	)
+	if c.SSLRootCert != "" {
+		pool, err := driver.CertPool(c.SSLRootCert)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrTLSConfig, err)
+		}
+		roots = pool
+	}
+	if c.SSLCert != "" {
+		pair, err := driver.KeyPair(c.SSLCert, c.SSLKey)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrTLSConfig, err)
+		}
+		certs = []tls.Certificate{pair}
+	}
+
+	configs := []*tls.Config{config.TLSConfig}
+	for _, fallback := range config.Fallbacks {
+		configs = append(configs, fallback.TLSConfig)
+	}
+
+	// verify-ca 的校验函数在握手时读取 RootCAs，因此直接修改 pgx 创建的 tls.Config
+	for _, tlsConfig := range configs {
+		if tlsConfig == nil {
+			continue
+		}
+		if roots != nil {
+			tlsConfig.RootCAs = roots
+		}
+		if certs != nil {
+			tlsConfig.Certificates = certs
+		}
+	}
+
+	return nil
+}
+
+// isInlinePEM 判断字符串是否为内联的 PEM 内容。
+func isInlinePEM(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), "-----BEGIN ")
+}
+
+// setDSNParams 在 DSN 中设置连接参数，DSN 可以是 URL 格式或关键字格式。
+func setDSNParams(dsn string, params map[string]string) (string, error) {
+	if len(params) == 0 {
+		return dsn, nil
+	}
+
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", err
+		}
+
+		query := u.Query()
+		for key, value := range params {
+			query.Set(key, value)
+		}
+		u.RawQuery = query.Encode()
+
+		return u.String(), nil
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// 关键字格式中后出现的参数覆盖先出现的参数
+	var b strings.Builder
+	b.WriteString(dsn)
+	for _, key := range keys {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+
+		value := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(params[key])
+		b.WriteString(key + "='" + value + "'")
+	}
+
+	return b.String(), nil
+}
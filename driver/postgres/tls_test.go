@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cotton-go/pkg/ssh"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/driver/postgres"
+)
+
+// selfSignedPEM 生成一个自签名证书及其私钥的 PEM 内容。
+func selfSignedPEM(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "db"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return string(certPEM), string(keyPEM)
+}
+
+func TestWithSSL(t *testing.T) {
+	certPEM, keyPEM := selfSignedPEM(t)
+
+	// pgx 只在 DSN 中写入 sslmode，证书直接设置到 tls.Config 上
+	conf, err := Config{DSN: "host=db user=app sslmode=disable", SSLMode: "require", SSLRootCert: certPEM}.withSSL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.DSN != "host=db user=app sslmode=disable sslmode='verify-ca'" {
+		t.Fatalf("unexpected dsn %s", conf.DSN)
+	}
+
+	config, err := pgconn.ParseConfig(conf.DSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.applyTLS(config); err != nil {
+		t.Fatal(err)
+	}
+	if config.TLSConfig == nil || config.TLSConfig.RootCAs == nil {
+		t.Fatal("root certificate not applied")
+	}
+
+	// lib/pq 通过 sslinline 内联证书
+	conf, err = Config{
+		DSN:         "postgres://app@db/app",
+		SSH:         &ssh.Config{Host: "bastion"},
+		SSLMode:     "verify-full",
+		SSLRootCert: certPEM,
+		SSLCert:     certPEM,
+		SSLKey:      keyPEM,
+	}.withSSL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(conf.DSN, "sslinline=true") || !strings.Contains(conf.DSN, "sslkey=-----BEGIN") {
+		t.Fatalf("unexpected dsn %s", conf.DSN)
+	}
+
+	_, err = Config{DSN: "host=db", SSH: &ssh.Config{Host: "bastion"}, SSLRootCert: certPEM}.withSSL()
+	if !errors.Is(err, ErrTLSConfig) {
+		t.Fatalf("got %v, want ErrTLSConfig", err)
+	}
+	_, err = NewWithError(Config{DSN: "host=db", SSLCert: certPEM})
+	if !errors.Is(err, ErrTLSConfig) {
+		t.Fatalf("got %v, want ErrTLSConfig", err)
+	}
+
+	dialector, err := NewWithError(Config{DSN: "host=db", SSLMode: "verify-full", SSLRootCert: certPEM, SSLCert: certPEM, SSLKey: keyPEM})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dialector.(*postgres.Dialector).Conn == nil {
+		t.Fatal("expected pgx connection pool")
+	}
+}
@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	"github.com/cotton-go/pkg/driver"
+	"github.com/cotton-go/pkg/ssh"
+)
+
+var (
+	// tunnels 记录本驱动从所有驱动共享的 SSH 连接注册表中获取的引用，相同配置的数据库共用一个 SSH 连接。
+	tunnels = ssh.DefaultRegistry.Group()
+
+	// mu 保护 registered，保证同一个驱动名只注册一次。
+	mu sync.Mutex
+	// registered 记录已经通过 sql.Register 注册的驱动名。
+	// database/sql 不支持注销驱动，SSH 连接关闭后驱动仍保留，再次获取连接时继续使用。
+	registered = make(map[string]struct{})
+)
+
+// connectTunnel 按重试策略调用 registerTunnel，SSH 认证失败时不会重试。
+// ctx 超过截止时间后不再开始新的尝试，正在进行的 SSH 连接不会被中断。
+//
+// 参数:
+//   - ctx: 上下文，通常由 Retry.Context 创建，与打开数据库共享总时长的截止时间。
+//   - conf: SSH 连接配置。
+//   - retry: 重试策略，未设置日志记录器时使用 SSH 配置中的日志记录器。
+//
+// 返回值:
+//   - string: SSH 连接的键。
+//   - error: 最后一次尝试的错误。
+func connectTunnel(ctx context.Context, conf ssh.Config, retry driver.Retry) (string, error) {
+	if retry.Logger == nil {
+		retry.Logger = conf.Logger
+	}
+
+	var key string
+	err := retry.Do(ctx, "ssh connect", func(ctx context.Context) error {
+		if err := ctx.Err(); err != nil {
+			return driver.Permanent(err)
+		}
+
+		var err error
+		key, err = registerTunnel(conf)
+		if errors.Is(err, ErrSSHAuth) {
+			return driver.Permanent(err)
+		}
+
+		return err
+	})
+
+	return key, err
+}
+
+// registerTunnel 获取 SSH 连接并注册对应的 SQL 驱动，相同配置只会注册一次。
+// 注册的驱动每次拨号时按键查找当前的 SSH 连接，因此重复调用 New 不会导致 sql.Register panic。
+//
+// 参数:
+//   - conf: SSH 连接配置。
+//
+// 返回值:
+//   - string: 注册的驱动名。
+//   - error: 如果建立 SSH 连接失败，则返回错误信息。
+func registerTunnel(conf ssh.Config) (string, error) {
+	// 建立 SSH 连接时不持有 mu，避免阻塞其他主机的连接
+	if _, err := tunnels.Acquire(conf); err != nil {
+		return "", err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	key := conf.Key()
+	if _, ok := registered[key]; !ok {
+		// 使用 SSH 连接注册新的 SQL 驱动。
+		sql.Register(key, &Dialector{key: key})
+		registered[key] = struct{}{}
+	}
+
+	return key, nil
+}
+
+// CloseTunnel 释放 SSH 配置对应的连接的一次引用。
+// 每次以该配置调用 New 都会增加一次引用，所有驱动的引用全部释放后关闭 SSH 连接。
+//
+// 参数:
+//   - conf: 创建数据库连接时使用的 SSH 配置。
+//
+// 返回值:
+//   - error: 如果关闭 SSH 连接失败，则返回错误信息。
+func CloseTunnel(conf ssh.Config) error {
+	_, err := tunnels.Release(conf.Key())
+	return err
+}
+
+// CloseTunnels 释放 PostgreSQL 驱动获取的所有 SSH 连接引用，通常在程序退出时调用。
+// 其他驱动仍在使用的 SSH 连接保持打开。
+//
+// 返回值:
+//   - error: 关闭过程中遇到的第一个错误。
+func CloseTunnels() error {
+	_, err := tunnels.Close()
+	return err
+}
+
+// closeTunnels 释放每个配置的 SSH 连接的一次引用，未设置 SSH 的配置会被跳过。
+func closeTunnels(confs ...Config) error {
+	var errs []error
+	for _, conf := range confs {
+		if conf.SSH != nil {
+			errs = append(errs, CloseTunnel(*conf.SSH))
+		}
+	}
+
+	return errors.Join(errs...)
+}
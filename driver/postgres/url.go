@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/cotton-go/pkg/driver"
+	"github.com/cotton-go/pkg/ssh"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// Scheme 是 PostgreSQL 连接 URL 的 scheme，通过 SSH 隧道连接时为 "postgres+ssh"。
+// 同时也支持 "postgresql" 与 "postgresql+ssh"。
+const Scheme = "postgres"
+
+func init() {
+	parse := func(rawURL string) (driver.Config, error) {
+		return ParseURL(rawURL)
+	}
+
+	driver.Register(Scheme, func() driver.Config { return &Config{} })
+	driver.RegisterURL(Scheme, parse)
+	driver.RegisterURL("postgresql", parse)
+}
+
+// ParseURL 将连接 URL 解析为 PostgreSQL 配置。
+// URL 的格式为 postgres[+ssh]://user:password@host:port/dbname?param=value，
+// 其中 ssh_ 开头的参数解析为 SSH 配置，其他参数与 libpq 的连接参数相同，例如 sslmode=disable。
+//
+// 参数:
+//   - rawURL: 连接 URL，例如 postgres+ssh://dbuser:pw@db:5432/app?ssh_host=bastion&ssh_user=ops&ssh_key=/keys/id。
+//
+// 返回值:
+//   - Config: 解析得到的配置，DSN 为去掉 SSH 参数后的 postgres:// URL。
+//   - error: 如果 URL 格式错误，则返回包装了 ErrDSNParse 的错误。
+func ParseURL(rawURL string) (Config, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Config{}, fmt.Errorf("%w: %w", ErrDSNParse, err)
+	}
+
+	scheme := strings.TrimSuffix(u.Scheme, "+ssh")
+	if scheme != Scheme && scheme != "postgresql" {
+		return Config{}, fmt.Errorf("%w: unexpected url scheme %q", ErrDSNParse, u.Scheme)
+	}
+
+	query := u.Query()
+	sshConf, err := ssh.ConfigFromQuery(query)
+	if err != nil {
+		return Config{}, fmt.Errorf("%w: %w", ErrDSNParse, err)
+	}
+	if sshConf == nil && scheme != u.Scheme {
+		return Config{}, fmt.Errorf("%w: %s is required for scheme %q", ErrDSNParse, ssh.QueryHost, u.Scheme)
+	}
+
+	u.Scheme = Scheme
+	u.RawQuery = query.Encode()
+	dsn := u.String()
+
+	// 提前校验剩余的连接参数，避免在 gorm.Open 时才发现格式错误
+	if _, err := pgconn.ParseConfig(dsn); err != nil {
+		return Config{}, fmt.Errorf("%w: %w", ErrDSNParse, err)
+	}
+
+	return Config{DSN: dsn, SSH: sshConf}, nil
+}
+
+// String 返回配置对应的连接 URL，数据库与 SSH 的密码会被替换为 ssh.RedactedSecret。
+// 关键字格式的 DSN 会转换为 URL 格式，返回的 URL 可以再次通过 ParseURL 解析，除密码外得到相同的配置。
+// DSN 无法解析时只返回 scheme。
+func (c Config) String() string {
+	scheme := Scheme
+	if c.SSH != nil {
+		scheme += "+ssh"
+	}
+
+	u, err := dsnURL(c.DSN)
+	if err != nil {
+		return scheme + "://"
+	}
+	u.Scheme = scheme
+
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), ssh.RedactedSecret)
+		}
+	}
+
+	query := u.Query()
+	if query.Has("password") {
+		query.Set("password", ssh.RedactedSecret)
+	}
+	if c.SSH != nil {
+		c.SSH.EncodeQuery(query, true)
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// Dialector 实现 driver.Config 接口，等同于 NewWithError(c)。
+func (c Config) Dialector() (gorm.Dialector, error) {
+	return NewWithError(c)
+}
+
+// dsnURL 将 DSN 转换为 URL，DSN 可以是 URL 格式，也可以是 host=localhost user=postgres 这样的关键字格式。
+func dsnURL(dsn string) (*url.URL, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return url.Parse(dsn)
+	}
+
+	params, err := parseKeywords(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &url.URL{Scheme: Scheme, Host: params["host"], Path: "/" + params["dbname"]}
+	if port := params["port"]; port != "" {
+		u.Host += ":" + port
+	}
+
+	if password, ok := params["password"]; ok {
+		u.User = url.UserPassword(params["user"], password)
+	} else if params["user"] != "" {
+		u.User = url.User(params["user"])
+	}
+
+	query := url.Values{}
+	for key, value := range params {
+		switch key {
+		case "host", "port", "dbname", "user", "password":
+		default:
+			query.Set(key, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u, nil
+}
+
+// parseKeywords 解析关键字格式的 DSN，值可以用单引号包裹，引号内使用反斜杠转义。
+func parseKeywords(dsn string) (map[string]string, error) {
+	params := make(map[string]string)
+
+	s := strings.TrimSpace(dsn)
+	for s != "" {
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			return nil, fmt.Errorf("missing \"=\" after %q in dsn", key)
+		}
+		key = strings.TrimSpace(key)
+		rest = strings.TrimLeft(rest, " \t\n\r")
+
+		var value strings.Builder
+		if strings.HasPrefix(rest, "'") {
+			i, closed := 1, false
+			for ; i < len(rest); i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				} else if rest[i] == '\'' {
+					closed = true
+					break
+				}
+				value.WriteByte(rest[i])
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated quoted value for %q in dsn", key)
+			}
+			rest = rest[i+1:]
+		} else {
+			end := strings.IndexAny(rest, " \t\n\r")
+			if end < 0 {
+				end = len(rest)
+			}
+			value.WriteString(rest[:end])
+			rest = rest[end:]
+		}
+
+		params[key] = value.String()
+		s = strings.TrimSpace(rest)
+	}
+
+	return params, nil
+}
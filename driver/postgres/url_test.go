@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cotton-go/pkg/driver"
+	"github.com/cotton-go/pkg/ssh"
+)
+
+func TestParseURL(t *testing.T) {
+	conf, err := ParseURL("postgresql+ssh://dbuser:pw@db:5432/app?sslmode=disable&ssh_host=bastion&ssh_port=2222&ssh_user=ops&ssh_password=secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if conf.DSN != "postgres://dbuser:pw@db:5432/app?sslmode=disable" {
+		t.Fatalf("unexpected dsn %s", conf.DSN)
+	}
+	if conf.SSH == nil || conf.SSH.Host != "bastion" || conf.SSH.Port != 2222 || conf.SSH.Password != "secret" {
+		t.Fatalf("unexpected ssh config %+v", conf.SSH)
+	}
+
+	want := "postgres+ssh://dbuser:xxxxx@db:5432/app?ssh_host=bastion&ssh_password=xxxxx&ssh_port=2222&ssh_user=ops&sslmode=disable"
+	if got := conf.String(); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	again, err := ParseURL(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.String() != want || again.SSH.Password != ssh.RedactedSecret {
+		t.Fatalf("round trip mismatch: %s", again.String())
+	}
+
+	for _, rawURL := range []string{"mysql://db/app", "postgres+ssh://db/app", "postgres://db/app?ssh_key=/keys/id"} {
+		if _, err := ParseURL(rawURL); !errors.Is(err, ErrDSNParse) {
+			t.Fatalf("%s: got %v, want ErrDSNParse", rawURL, err)
+		}
+	}
+}
+
+func TestStringKeywordDSN(t *testing.T) {
+	conf := Config{DSN: `host=db port=5432 user=dbuser password='p w\'d' dbname=app sslmode=disable`}
+
+	want := "postgres://dbuser:xxxxx@db:5432/app?sslmode=disable"
+	if got := conf.String(); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	params, err := parseKeywords(conf.DSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params["password"] != "p w'd" {
+		t.Fatalf("got password %q", params["password"])
+	}
+
+	if _, err := parseKeywords("host='db"); err == nil {
+		t.Fatal("expected error for unterminated quote")
+	}
+}
+
+func TestDecodeConfigFile(t *testing.T) {
+	raw := []byte(`
+driverName: postgres
+dsn: postgres://app@db:5432/app
+backend: 1
+maxOpenConns: 20
+connMaxLifetime: 10m
+connMaxIdleTime: 90s
+sslMode: verify-full
+sslRootCert: /etc/ssl/ca.pem
+retry:
+  maxAttempts: 5
+  initialBackoff: 500ms
+  maxBackoff: 10s
+ssh:
+  host: bastion
+  port: 2222
+  type: private_key_path
+  user: ops
+  privateKeyPath: /keys/id_ed25519
+replicas:
+  - dsn: postgres://app@replica:5432/app
+    connMaxLifetime: 1h
+    ssh:
+      host: bastion
+      user: ops
+      type: password
+      password: secret
+`)
+
+	conf, err := driver.Decode(Scheme, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := conf.(*Config)
+	if got.DSN != "postgres://app@db:5432/app" || got.Backend != BackendPGX || got.MaxOpenConns != 20 || got.SSLMode != "verify-full" {
+		t.Fatalf("unexpected config %+v", got)
+	}
+	if got.ConnMaxLifetime != 10*time.Minute || got.ConnMaxIdleTime != 90*time.Second {
+		t.Fatalf("unexpected durations %s %s", got.ConnMaxLifetime, got.ConnMaxIdleTime)
+	}
+	if got.Retry.MaxAttempts != 5 || got.Retry.InitialBackoff != 500*time.Millisecond || got.Retry.MaxBackoff != 10*time.Second {
+		t.Fatalf("unexpected retry %+v", got.Retry)
+	}
+	if got.SSH == nil || got.SSH.Port != 2222 || got.SSH.Type != ssh.ConfigTypeByPrivateKeyPath || got.SSH.PrivateKeyPath != "/keys/id_ed25519" {
+		t.Fatalf("unexpected ssh config %+v", got.SSH)
+	}
+	if len(got.Replicas) != 1 || got.Replicas[0].ConnMaxLifetime != time.Hour || got.Replicas[0].SSH.Type != ssh.ConfigTypeByPassword {
+		t.Fatalf("unexpected replicas %+v", got.Replicas)
+	}
+
+	if _, err := driver.Decode(Scheme, []byte("connMaxLifetime: ten minutes")); err == nil {
+		t.Fatal("expected error for invalid duration")
+	}
+	if _, err := driver.Decode(Scheme, []byte("ssh:\n  type: certificate")); err == nil {
+		t.Fatal("expected error for unknown ssh auth type")
+	}
+}
@@ -0,0 +1,235 @@
+package driver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// Register 注册一种数据库驱动，通常在驱动包的 init 函数中调用。
+// 重复注册同一个名称或 newConfig 为 nil 时会 panic。
+//
+// 参数:
+//   - kind: 驱动名称，例如 "mysql"，与 Open 的 kind 参数对应。
+//   - newConfig: 返回一个空配置指针的函数，例如 func() driver.Config { return &Config{} }，原始配置会被解码到其中。
+func Register(kind string, newConfig func() Config) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if newConfig == nil {
+		panic("driver: Register newConfig is nil")
+	}
+	if _, dup := configs[kind]; dup {
+		panic("driver: Register called twice for driver " + kind)
+	}
+
+	configs[kind] = newConfig
+}
+
+// Kinds 返回所有已注册的驱动名称，按字母顺序排列。
+func Kinds() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	kinds := make([]string, 0, len(configs))
+	for kind := range configs {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	return kinds
+}
+
+// Decode 将 JSON 或 YAML 格式的原始配置解码为指定驱动的配置。
+// 字段名称使用各驱动 Config 的 json 标签，YAML 会先转换为 JSON 再解码，因此两种格式的字段名称一致。
+// time.Duration 类型的字段既可以是纳秒数，也可以是 time.ParseDuration 支持的字符串，例如 "30s"、"5m"。
+//
+// 参数:
+//   - kind: 驱动名称，需要先导入对应的驱动包，例如 import _ "github.com/cotton-go/pkg/driver/mysql"。
+//   - rawConfig: JSON 或 YAML 格式的配置。
+//
+// 返回值:
+//   - Config: 驱动的配置，为具体驱动 Config 的指针，例如 *mysql.Config。
+//   - error: 如果驱动未注册或配置无法解码，则返回错误信息。
+func Decode(kind string, rawConfig []byte) (Config, error) {
+	mu.RLock()
+	newConfig, ok := configs[kind]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown driver %q (forgotten import?)", kind)
+	}
+
+	data, err := toJSON(rawConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode %s config: %w", kind, err)
+	}
+
+	conf := newConfig()
+	if data, err = normalizeDurations(data, reflect.TypeOf(conf)); err != nil {
+		return nil, fmt.Errorf("unable to decode %s config: %w", kind, err)
+	}
+	if err := json.Unmarshal(data, conf); err != nil {
+		return nil, fmt.Errorf("unable to decode %s config: %w", kind, err)
+	}
+
+	return conf, nil
+}
+
+// Open 将原始配置解码为指定驱动的配置，并创建 Gorm 数据库连接器。
+// 同一份配置文件只需修改 kind 即可切换数据库。
+//
+// 参数:
+//   - kind: 驱动名称，例如 "mysql"、"postgres"。
+//   - rawConfig: JSON 或 YAML 格式的配置。
+//
+// 返回值:
+//   - gorm.Dialector: 用于 Gorm 以建立数据库连接的接口。
+//   - error: 如果配置无法解码或创建连接器失败，则返回错误信息。
+func Open(kind string, rawConfig []byte) (gorm.Dialector, error) {
+	conf, err := Decode(kind, rawConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return conf.Dialector()
+}
+
+// toJSON 将 JSON 或 YAML 格式的配置统一转换为 JSON。
+func toJSON(rawConfig []byte) ([]byte, error) {
+	if json.Valid(rawConfig) {
+		return rawConfig, nil
+	}
+
+	var v any
+	if err := yaml.Unmarshal(rawConfig, &v); err != nil {
+		return nil, err
+	}
+	if v == nil {
+		// 空配置等同于 {}
+		return []byte("{}"), nil
+	}
+
+	return json.Marshal(v)
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// normalizeDurations 将 JSON 配置中对应 time.Duration 字段的字符串转换为纳秒数，使 encoding/json 可以解码。
+//
+// 参数:
+//   - data: JSON 格式的配置。
+//   - typ: 配置的类型，用于查找 time.Duration 字段。
+//
+// 返回值:
+//   - []byte: 转换后的 JSON 配置。
+//   - error: 如果 JSON 无效或时长字符串无法解析，则返回错误信息。
+func normalizeDurations(data []byte, typ reflect.Type) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	v, err := normalizeValue(v, typ, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v)
+}
+
+// normalizeValue 按照 typ 递归遍历 v，将 time.Duration 字段的字符串值替换为纳秒数，path 用于错误信息。
+func normalizeValue(v any, typ reflect.Type, path string) (any, error) {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch val := v.(type) {
+	case string:
+		if typ != durationType {
+			return v, nil
+		}
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return int64(d), nil
+	case []any:
+		if typ.Kind() != reflect.Slice && typ.Kind() != reflect.Array {
+			return v, nil
+		}
+		for i := range val {
+			elem, err := normalizeValue(val[i], typ.Elem(), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			val[i] = elem
+		}
+	case map[string]any:
+		for key := range val {
+			field, ok := fieldType(typ, key)
+			if !ok {
+				continue
+			}
+			elem, err := normalizeValue(val[key], field, strings.TrimPrefix(path+"."+key, "."))
+			if err != nil {
+				return nil, err
+			}
+			val[key] = elem
+		}
+	}
+
+	return v, nil
+}
+
+// fieldType 返回 typ 中与 JSON 键 key 对应的字段类型，匹配规则与 encoding/json 一致，不区分大小写。
+// typ 为 map 时返回其值的类型。
+func fieldType(typ reflect.Type, key string) (reflect.Type, bool) {
+	switch typ.Kind() {
+	case reflect.Map:
+		return typ.Elem(), true
+	case reflect.Struct:
+	default:
+		return nil, false
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if t, ok := fieldType(embedded, key); ok {
+					return t, true
+				}
+				continue
+			}
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		if strings.EqualFold(name, key) {
+			return field.Type, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,54 @@
+package driver
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// testConfig 是一个只用于测试注册与解码的驱动配置。
+type testConfig struct {
+	DSN     string        `json:"dsn"`
+	Port    int           `json:"port"`
+	Timeout time.Duration `json:"timeout"`
+	SSH     *struct {
+		Host string
+	} `json:"ssh"`
+}
+
+func (c testConfig) Dialector() (gorm.Dialector, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestDecode(t *testing.T) {
+	Register("test", func() Config { return &testConfig{} })
+
+	jsonConf := []byte(`{"dsn": "file.db", "port": 3306, "timeout": 1500000000, "ssh": {"host": "bastion"}}`)
+	yamlConf := []byte("dsn: file.db\nport: 3306\ntimeout: 1.5s\nssh:\n  host: bastion\n")
+	for _, raw := range [][]byte{jsonConf, yamlConf} {
+		conf, err := Decode("test", raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := conf.(*testConfig)
+		if got.DSN != "file.db" || got.Port != 3306 || got.Timeout != 1500*time.Millisecond || got.SSH == nil || got.SSH.Host != "bastion" {
+			t.Fatalf("unexpected config %+v", got)
+		}
+	}
+
+	if _, err := Decode("unknown", jsonConf); err == nil {
+		t.Fatal("expected error for unknown driver")
+	}
+	if _, err := Decode("test", []byte("port: [")); err == nil {
+		t.Fatal("expected error for invalid yaml")
+	}
+	if _, err := Decode("test", []byte("timeout: soon")); err == nil {
+		t.Fatal("expected error for invalid duration")
+	}
+	if _, err := Open("test", nil); err == nil {
+		t.Fatal("expected error from dialector")
+	}
+}
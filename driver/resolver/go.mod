@@ -0,0 +1,19 @@
+module github.com/cotton-go/pkg/driver/resolver
+
+go 1.21
+
+require (
+	gorm.io/gorm v1.25.11
+	gorm.io/plugin/dbresolver v1.5.2
+)
+
+require gopkg.in/yaml.v3 v3.0.1 // indirect
+
+require (
+	github.com/cotton-go/pkg/driver v0.0.0
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
+
+replace github.com/cotton-go/pkg/driver => ../
@@ -0,0 +1,133 @@
+package resolver
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultProbeInterval 是探测只读副本延迟的默认间隔。
+const DefaultProbeInterval = 10 * time.Second
+
+// unreachable 表示探测失败的只读副本的延迟，只有所有副本都不可用时才会被选中。
+const unreachable = time.Duration(math.MaxInt64)
+
+// pinger 是支持 PingContext 的连接池，例如 *sql.DB。
+type pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// LeastLatencyPolicy 选择延迟最低的只读副本。
+// 延迟通过 PingContext 探测，距上次探测超过间隔时在后台重新探测，不会阻塞查询。
+// 尚未得到探测结果时轮流选择只读副本。
+type LeastLatencyPolicy struct {
+	interval time.Duration
+
+	mu        sync.Mutex
+	latencies map[gorm.ConnPool]time.Duration // 每个副本平滑后的延迟
+	probedAt  time.Time                       // 上次探测完成的时间
+	probing   bool                            // 是否正在探测
+	next      int                             // 没有探测结果时轮流选择的位置
+}
+
+// NewLeastLatencyPolicy 创建一个 LeastLatencyPolicy 实例。
+//
+// 参数:
+//   - interval: 探测延迟的间隔，小于等于 0 时使用 DefaultProbeInterval。
+//
+// 返回值:
+//   - *LeastLatencyPolicy: 创建的策略。
+func NewLeastLatencyPolicy(interval time.Duration) *LeastLatencyPolicy {
+	if interval <= 0 {
+		interval = DefaultProbeInterval
+	}
+
+	return &LeastLatencyPolicy{interval: interval, latencies: make(map[gorm.ConnPool]time.Duration)}
+}
+
+// Resolve 实现 dbresolver.Policy 接口，返回延迟最低的只读副本。
+func (p *LeastLatencyPolicy) Resolve(connPools []gorm.ConnPool) gorm.ConnPool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.probing && time.Since(p.probedAt) >= p.interval {
+		p.probing = true
+		go p.probe(connPools)
+	}
+
+	var (
+		best    gorm.ConnPool
+		bestLat time.Duration
+	)
+	for _, connPool := range connPools {
+		latency, ok := p.latencies[connPool]
+		if !ok {
+			continue
+		}
+
+		if best == nil || latency < bestLat {
+			best, bestLat = connPool, latency
+		}
+	}
+
+	if best == nil {
+		p.next = (p.next + 1) % len(connPools)
+		return connPools[p.next]
+	}
+
+	return best
+}
+
+// probe 并发探测所有只读副本的延迟，并与之前的结果做指数平滑。
+func (p *LeastLatencyPolicy) probe(connPools []gorm.ConnPool) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results = make(map[gorm.ConnPool]time.Duration, len(connPools))
+	)
+
+	for _, connPool := range connPools {
+		db, ok := connPool.(pinger)
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(connPool gorm.ConnPool, db pinger) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), p.interval)
+			defer cancel()
+
+			start := time.Now()
+			latency := unreachable
+			if err := db.PingContext(ctx); err == nil {
+				latency = time.Since(start)
+			}
+
+			mu.Lock()
+			results[connPool] = latency
+			mu.Unlock()
+		}(connPool, db)
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	latencies := make(map[gorm.ConnPool]time.Duration, len(results))
+	for connPool, latency := range results {
+		// 两次探测都成功时做指数平滑，减少偶发抖动的影响
+		if prev, ok := p.latencies[connPool]; ok && prev != unreachable && latency != unreachable {
+			latency = (prev*7 + latency*3) / 10
+		}
+		latencies[connPool] = latency
+	}
+
+	p.latencies = latencies
+	p.probedAt = time.Now()
+	p.probing = false
+}
@@ -0,0 +1,165 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cotton-go/pkg/driver"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// Policy 定义从多个只读副本中选择连接的策略。
+type Policy string
+
+const (
+	// PolicyRandom 表示随机选择只读副本，为默认策略。
+	PolicyRandom Policy = "random"
+	// PolicyRoundRobin 表示轮流选择只读副本。
+	PolicyRoundRobin Policy = "round_robin"
+	// PolicyLeastLatency 表示选择最近一次探测中延迟最低的只读副本。
+	PolicyLeastLatency Policy = "least_latency"
+)
+
+// New 创建一个读写分离的 Gorm 插件。
+// 查询语句路由到只读副本，写入语句与事务始终路由到主库，即 gorm.Open 时使用的连接。
+//
+// 参数:
+//   - replicas: 只读副本的数据库连接器。
+//   - policy: 选择只读副本的策略，为空时使用 PolicyRandom。
+//   - pool: 只读副本的连接池参数，只应用到副本的连接池上，主库的连接池保持不变。
+//
+// 返回值:
+//   - gorm.Plugin: 通过 db.Use 注册的插件。
+//   - error: 策略名称无法识别时返回错误。
+func New(replicas []gorm.Dialector, policy Policy, pool driver.Pool) (gorm.Plugin, error) {
+	p, err := policy.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	if pool != (driver.Pool{}) {
+		// plugin.Call 会同时作用于主库，因此在每个副本的连接池创建之后单独应用
+		pooled := make([]gorm.Dialector, len(replicas))
+		for i, replica := range replicas {
+			pooled[i] = pooledDialector{Dialector: replica, pool: pool}
+		}
+		replicas = pooled
+	}
+
+	return dbresolver.Register(dbresolver.Config{Replicas: replicas, Policy: p}), nil
+}
+
+// pooledDialector 在副本初始化、连接池创建之后应用连接池参数。
+type pooledDialector struct {
+	gorm.Dialector
+	pool driver.Pool
+}
+
+// Initialize 初始化副本的连接，成功后将连接池参数应用到副本的连接池上。
+func (d pooledDialector) Initialize(db *gorm.DB) error {
+	if err := d.Dialector.Initialize(db); err != nil {
+		return err
+	}
+
+	d.pool.Apply(db.ConnPool)
+	return nil
+}
+
+// resolve 返回策略对应的 dbresolver.Policy 实现。
+func (p Policy) resolve() (dbresolver.Policy, error) {
+	switch p {
+	case "", PolicyRandom:
+		return dbresolver.RandomPolicy{}, nil
+	case PolicyRoundRobin:
+		return dbresolver.StrictRoundRobinPolicy(), nil
+	case PolicyLeastLatency:
+		return NewLeastLatencyPolicy(DefaultProbeInterval), nil
+	default:
+		return nil, fmt.Errorf("unknown replica policy %q", string(p))
+	}
+}
+
+// Replica 是可以作为只读副本的驱动配置，mysql.Config 与 postgres.Config 实现了该接口。
+type Replica interface {
+	driver.Config
+	// Tunneled 返回副本是否通过 SSH 隧道连接。
+	Tunneled() bool
+	// ReleaseTunnel 释放 Dialector 获取的 SSH 连接引用，未使用 SSH 时不做任何操作。
+	ReleaseTunnel() error
+}
+
+// NewFromConfigs 根据只读副本的配置创建读写分离插件，供各驱动的 NewResolver 使用。
+// 任意副本使用 SSH 时，连接池中未设置的生命周期使用隧道的默认值。
+// 创建失败时释放已创建的副本获取的 SSH 连接引用。
+//
+// 参数:
+//   - replicas: 只读副本的配置。
+//   - policy: 选择只读副本的策略，为空时使用 PolicyRandom。
+//   - pool: 只读副本的连接池参数，通常取自主库的配置，只应用到副本的连接池上。
+//
+// 返回值:
+//   - gorm.Plugin: 通过 db.Use 注册的插件。
+//   - error: 副本的连接器创建失败或策略无法识别时返回错误。
+func NewFromConfigs[R Replica](replicas []R, policy Policy, pool driver.Pool) (gorm.Plugin, error) {
+	dialectors := make([]gorm.Dialector, 0, len(replicas))
+	for i, replica := range replicas {
+		if replica.Tunneled() {
+			pool = pool.Tunneled()
+		}
+
+		dialector, err := replica.Dialector()
+		if err != nil {
+			_ = release(replicas[:i])
+			return nil, err
+		}
+
+		dialectors = append(dialectors, dialector)
+	}
+
+	plugin, err := New(dialectors, policy, pool)
+	if err != nil {
+		_ = release(replicas)
+		return nil, err
+	}
+
+	return plugin, nil
+}
+
+// Use 根据只读副本的配置创建读写分离插件并注册到 db 上，没有副本时不做任何操作。
+// 注册失败时释放副本获取的 SSH 连接引用。
+//
+// 参数:
+//   - db: 主库的数据库连接。
+//   - replicas: 只读副本的配置。
+//   - policy: 选择只读副本的策略，为空时使用 PolicyRandom。
+//   - pool: 只读副本的连接池参数。
+//
+// 返回值:
+//   - error: 创建或注册插件失败时返回错误。
+func Use[R Replica](db *gorm.DB, replicas []R, policy Policy, pool driver.Pool) error {
+	if len(replicas) == 0 {
+		return nil
+	}
+
+	plugin, err := NewFromConfigs(replicas, policy, pool)
+	if err != nil {
+		return err
+	}
+	if err := db.Use(plugin); err != nil {
+		_ = release(replicas)
+		return err
+	}
+
+	return nil
+}
+
+// release 释放副本获取的 SSH 连接引用。
+func release[R Replica](replicas []R) error {
+	var errs []error
+	for _, replica := range replicas {
+		errs = append(errs, replica.ReleaseTunnel())
+	}
+
+	return errors.Join(errs...)
+}
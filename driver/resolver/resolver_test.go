@@ -0,0 +1,205 @@
+package resolver
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cotton-go/pkg/driver"
+	"gorm.io/gorm"
+	"gorm.io/gorm/utils/tests"
+)
+
+// fakePool 是一个按固定延迟响应 PingContext 的连接池。
+type fakePool struct {
+	gorm.ConnPool
+	name    string
+	latency time.Duration
+	down    bool
+}
+
+func (p *fakePool) PingContext(ctx context.Context) error {
+	if p.down {
+		return errors.New("down")
+	}
+
+	time.Sleep(p.latency)
+	return nil
+}
+
+func TestLeastLatencyPolicy(t *testing.T) {
+	slow := &fakePool{name: "slow", latency: 20 * time.Millisecond}
+	fast := &fakePool{name: "fast", latency: time.Millisecond}
+	down := &fakePool{name: "down", down: true}
+	pools := []gorm.ConnPool{slow, down, fast}
+
+	policy := NewLeastLatencyPolicy(time.Hour)
+	// 第一次选择时尚无探测结果，同时触发后台探测
+	if policy.Resolve(pools) == nil {
+		t.Fatal("resolve returned nil")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if got := policy.Resolve(pools).(*fakePool); got == fast {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("got %s, want fast", got.name)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	for _, policy := range []Policy{"", PolicyRandom, PolicyRoundRobin, PolicyLeastLatency} {
+		if _, err := New(nil, policy, driver.Pool{}); err != nil {
+			t.Fatalf("policy %q: %v", policy, err)
+		}
+	}
+
+	if _, err := New(nil, "fastest", driver.Pool{}); err == nil {
+		t.Fatal("expected error for unknown policy")
+	}
+}
+
+// nopConnector 是一个不会被实际拨号的连接器，只用于创建 *sql.DB。
+type nopConnector struct{}
+
+func (nopConnector) Connect(context.Context) (sqldriver.Conn, error) {
+	return nil, errors.New("not implemented")
+}
+func (nopConnector) Driver() sqldriver.Driver { return nil }
+
+// connDialector 初始化时使用给定的 *sql.DB 作为连接池。
+type connDialector struct {
+	tests.DummyDialector
+	conn *sql.DB
+}
+
+func (d connDialector) Initialize(db *gorm.DB) error {
+	db.ConnPool = d.conn
+	return d.DummyDialector.Initialize(db)
+}
+
+func TestNewPoolAppliesToReplicasOnly(t *testing.T) {
+	primary := sql.OpenDB(nopConnector{})
+	defer primary.Close()
+	replica := sql.OpenDB(nopConnector{})
+	defer replica.Close()
+
+	db, err := gorm.Open(connDialector{conn: primary}, &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plugin, err := New([]gorm.Dialector{connDialector{conn: replica}}, PolicyRandom, driver.Pool{MaxOpenConns: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(plugin); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := replica.Stats().MaxOpenConnections; got != 7 {
+		t.Fatalf("replica max open = %d, want 7", got)
+	}
+	if got := primary.Stats().MaxOpenConnections; got != 0 {
+		t.Fatalf("primary max open = %d, want unchanged 0", got)
+	}
+}
+
+func TestConnPoolsIncludesReplicas(t *testing.T) {
+	primary := sql.OpenDB(nopConnector{})
+	defer primary.Close()
+	replica := sql.OpenDB(nopConnector{})
+	defer replica.Close()
+
+	db, err := gorm.Open(connDialector{conn: primary}, &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plugin, err := New([]gorm.Dialector{connDialector{conn: replica}}, PolicyRandom, driver.Pool{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(plugin); err != nil {
+		t.Fatal(err)
+	}
+
+	pools, err := driver.ConnPools(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pools) != 2 || pools[0] != primary || pools[1] != replica {
+		t.Fatalf("pools = %v, want primary and replica", pools)
+	}
+}
+
+// fakeReplica 是一个记录 SSH 连接引用获取与释放次数的副本配置。
+type fakeReplica struct {
+	dialector gorm.Dialector
+	err       error
+	refs      *int
+}
+
+func (r fakeReplica) Dialector() (gorm.Dialector, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	*r.refs++
+	return r.dialector, nil
+}
+
+func (r fakeReplica) Tunneled() bool { return true }
+
+func (r fakeReplica) ReleaseTunnel() error {
+	if *r.refs > 0 {
+		*r.refs--
+	}
+	return nil
+}
+
+func TestNewFromConfigs(t *testing.T) {
+	replica := sql.OpenDB(nopConnector{})
+	defer replica.Close()
+
+	// 后续副本失败时释放已创建副本的引用
+	var refs int
+	failed := errors.New("dial failed")
+	replicas := []fakeReplica{{dialector: connDialector{conn: replica}, refs: &refs}, {err: failed, refs: &refs}}
+	if _, err := NewFromConfigs(replicas, PolicyRandom, driver.Pool{}); !errors.Is(err, failed) {
+		t.Fatalf("got %v, want %v", err, failed)
+	}
+	if refs != 0 {
+		t.Fatalf("refs = %d after failure, want 0", refs)
+	}
+
+	// 策略无法识别时释放全部副本的引用
+	if _, err := NewFromConfigs(replicas[:1], "fastest", driver.Pool{}); err == nil {
+		t.Fatal("expected error for unknown policy")
+	}
+	if refs != 0 {
+		t.Fatalf("refs = %d after unknown policy, want 0", refs)
+	}
+
+	// 连接池参数应用到副本上
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Use(db, replicas[:1], PolicyRandom, driver.Pool{MaxOpenConns: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if refs != 1 {
+		t.Fatalf("refs = %d, want 1", refs)
+	}
+	if got := replica.Stats().MaxOpenConnections; got != 3 {
+		t.Fatalf("replica max open = %d, want 3", got)
+	}
+}
@@ -0,0 +1,168 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+)
+
+// 重试的默认等待时间。
+const (
+	DefaultInitialBackoff = 500 * time.Millisecond // 第一次重试前的默认等待时间
+	DefaultMaxBackoff     = 30 * time.Second       // 两次尝试之间的默认最长等待时间
+)
+
+// Retry 定义启动时建立 SSH 连接与数据库连接的重试策略。
+// 容器启动时跳板机或数据库可能尚不可用，重试可以避免程序立即失败。
+// 每次重试的等待时间按指数增长，并加入随机抖动，避免多个实例同时重连。
+type Retry struct {
+	MaxAttempts    int           `json:"maxAttempts,omitempty"`    // 最大尝试次数，默认为 0，即只尝试一次，不重试。
+	InitialBackoff time.Duration `json:"initialBackoff,omitempty"` // 第一次重试前的等待时间，默认为 DefaultInitialBackoff。
+	MaxBackoff     time.Duration `json:"maxBackoff,omitempty"`     // 两次尝试之间的最长等待时间，默认为 DefaultMaxBackoff。
+	Deadline       time.Duration `json:"deadline,omitempty"`       // 所有尝试的总时长上限，打开数据库时 SSH 连接与数据库连接两个阶段共享该上限，默认为 0，即不限制，正在进行的 SSH 连接不会被中断。
+	Logger         *slog.Logger  `json:"-"`                        // 记录每次失败尝试的日志记录器，为 nil 时不输出日志。
+}
+
+// permanentError 表示不需要重试的错误。
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 将错误标记为不需要重试，例如 DSN 格式错误或认证失败，Do 会立即返回原始错误。
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+// Context 返回带有 Deadline 截止时间的上下文，未设置 Deadline 时返回可以取消的 parent。
+// 将同一个上下文传给多次 Do 时，Deadline 限制的是这些阶段的总时长，而不是每个阶段各自的时长。
+//
+// 参数:
+//   - parent: 父上下文。
+//
+// 返回值:
+//   - context.Context: 带有截止时间的上下文。
+//   - context.CancelFunc: 释放上下文资源的函数，使用完毕后需要调用。
+func (r Retry) Context(parent context.Context) (context.Context, context.CancelFunc) {
+	if r.Deadline > 0 {
+		return context.WithTimeout(parent, r.Deadline)
+	}
+
+	return context.WithCancel(parent)
+}
+
+// Do 按重试策略执行 fn，直到成功、遇到 Permanent 错误、达到最大尝试次数或超过总时长。
+// 每次失败都会记录一条日志，最终返回最后一次尝试的错误。
+// ctx 已经带有更早的截止时间时以 ctx 为准，因此多个阶段可以通过 Context 共享同一个总时长。
+//
+// 参数:
+//   - ctx: 上下文，取消后不再重试。
+//   - op: 操作名称，用于日志与错误信息，例如 "ssh connect"。
+//   - fn: 要执行的操作，ctx 包含了总时长的截止时间。
+//
+// 返回值:
+//   - error: 最后一次尝试的错误，可以通过 errors.Is 与原始错误比较。
+func (r Retry) Do(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	if r.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Deadline)
+		defer cancel()
+	}
+
+	logger := r.Logger
+	if logger == nil {
+		logger = slog.New(discardHandler{})
+	}
+
+	maxAttempts := max(r.MaxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		err := fn(ctx)
+		if err == nil {
+			if attempt > 1 {
+				logger.Info("driver retry succeeded", slog.String("operation", op), slog.Int("attempt", attempt))
+			}
+			return nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			logger.Error("driver attempt failed permanently",
+				slog.String("operation", op),
+				slog.Int("attempt", attempt),
+				slog.Any("error", permanent.err),
+			)
+			return permanent.err
+		}
+
+		if attempt >= maxAttempts {
+			logger.Error("driver attempt failed",
+				slog.String("operation", op),
+				slog.Int("attempt", attempt),
+				slog.Int("maxAttempts", maxAttempts),
+				slog.Duration("duration", time.Since(start)),
+				slog.Any("error", err),
+			)
+			if attempt > 1 {
+				return fmt.Errorf("%s failed after %d attempts: %w", op, attempt, err)
+			}
+			return err
+		}
+
+		backoff := r.backoff(attempt)
+		logger.Warn("driver attempt failed, retrying",
+			slog.String("operation", op),
+			slog.Int("attempt", attempt),
+			slog.Int("maxAttempts", maxAttempts),
+			slog.Duration("duration", time.Since(start)),
+			slog.Duration("backoff", backoff),
+			slog.Any("error", err),
+		)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s failed after %d attempts: %w", op, attempt, err)
+		case <-timer.C:
+		}
+	}
+}
+
+// discardHandler 是一个丢弃所有日志记录的 slog.Handler。
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// backoff 返回第 attempt 次失败后的等待时间，为指数增长的时长的一半加上随机的另一半。
+func (r Retry) backoff(attempt int) time.Duration {
+	initial := r.InitialBackoff
+	if initial <= 0 {
+		initial = DefaultInitialBackoff
+	}
+	limit := r.MaxBackoff
+	if limit <= 0 {
+		limit = DefaultMaxBackoff
+	}
+
+	d := initial
+	for i := 1; i < attempt && d < limit; i++ {
+		d *= 2
+	}
+	d = min(d, limit)
+
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
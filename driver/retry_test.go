@@ -0,0 +1,85 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	errDown := errors.New("down")
+	retry := Retry{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	attempts := 0
+	err := retry.Do(context.Background(), "test", func(context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errDown
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Fatalf("got %v after %d attempts", err, attempts)
+	}
+
+	attempts = 0
+	err = retry.Do(context.Background(), "test", func(context.Context) error {
+		attempts++
+		return errDown
+	})
+	if !errors.Is(err, errDown) || attempts != 3 {
+		t.Fatalf("got %v after %d attempts", err, attempts)
+	}
+
+	// Permanent 错误不会重试，返回原始错误
+	attempts = 0
+	err = retry.Do(context.Background(), "test", func(context.Context) error {
+		attempts++
+		return Permanent(errDown)
+	})
+	if err != errDown || attempts != 1 {
+		t.Fatalf("got %v after %d attempts", err, attempts)
+	}
+
+	// 超过总时长后停止重试
+	retry = Retry{MaxAttempts: 100, InitialBackoff: 20 * time.Millisecond, Deadline: 50 * time.Millisecond, Logger: retry.Logger}
+	start := time.Now()
+	err = retry.Do(context.Background(), "test", func(context.Context) error {
+		return errDown
+	})
+	if !errors.Is(err, errDown) || time.Since(start) > time.Second {
+		t.Fatalf("got %v after %v", err, time.Since(start))
+	}
+
+	// 通过 Context 共享的截止时间限制多个阶段的总时长
+	retry.Deadline = 200 * time.Millisecond
+	ctx, cancel := retry.Context(context.Background())
+	defer cancel()
+	start = time.Now()
+	for _, op := range []string{"ssh connect", "open database"} {
+		_ = retry.Do(ctx, op, func(context.Context) error {
+			time.Sleep(60 * time.Millisecond)
+			return errDown
+		})
+	}
+	if elapsed := time.Since(start); elapsed > 390*time.Millisecond {
+		t.Fatalf("two phases took %v, want at most one deadline", elapsed)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	retry := Retry{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		got := retry.backoff(attempt)
+		if got < want/2 || got > want {
+			t.Fatalf("attempt %d: got %v, want between %v and %v", attempt, got, want/2, want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+module github.com/cotton-go/pkg/driver/sqlite
+
+go 1.21
+
+require (
+	github.com/glebarez/sqlite v1.11.0
+	gorm.io/gorm v1.25.11
+)
+
+require gopkg.in/yaml.v3 v3.0.1 // indirect
+
+require (
+	github.com/cotton-go/pkg/driver v0.0.0
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/glebarez/go-sqlite v1.21.2 // indirect
+	github.com/google/uuid v1.3.0 // indirect
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	github.com/mattn/go-isatty v0.0.17 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	golang.org/x/sys v0.7.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	modernc.org/libc v1.22.5 // indirect
+	modernc.org/mathutil v1.5.0 // indirect
+	modernc.org/memory v1.5.0 // indirect
+	modernc.org/sqlite v1.23.1 // indirect
+)
+
+replace github.com/cotton-go/pkg/driver => ../
@@ -0,0 +1,133 @@
+package sqlite
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cotton-go/pkg/driver"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// JournalMode 定义 SQLite 的日志模式，对应 PRAGMA journal_mode。
+type JournalMode string
+
+const (
+	// JournalModeDelete 表示事务结束后删除回滚日志，为 SQLite 的默认模式。
+	JournalModeDelete JournalMode = "DELETE"
+	// JournalModeTruncate 表示事务结束后截断回滚日志。
+	JournalModeTruncate JournalMode = "TRUNCATE"
+	// JournalModePersist 表示事务结束后保留回滚日志并清空其头部。
+	JournalModePersist JournalMode = "PERSIST"
+	// JournalModeMemory 表示回滚日志保存在内存中。
+	JournalModeMemory JournalMode = "MEMORY"
+	// JournalModeWAL 表示使用预写日志，读写可以并发进行。
+	JournalModeWAL JournalMode = "WAL"
+	// JournalModeOff 表示不使用回滚日志。
+	JournalModeOff JournalMode = "OFF"
+)
+
+// defaultMemoryName 内存数据库未指定名称时使用的名称。
+const defaultMemoryName = "memdb"
+
+// Config 定义 SQLite 数据库驱动程序的配置选项。
+// 底层使用纯 Go 实现的驱动，不依赖 cgo。
+type Config struct {
+	DriverName  string        `json:"driverName"`            // 驱动名称，默认为 "sqlite"。
+	DSN         string        `json:"dsn,omitempty"`         // 数据库文件路径或 file: URI，内存数据库时为数据库名称，默认为空。
+	Conn        gorm.ConnPool `json:"connPool,omitempty"`    // 连接池，默认为空。
+	InMemory    bool          `json:"inMemory"`              // 使用内存数据库，默认为 false。
+	SharedCache bool          `json:"sharedCache"`           // 使用共享缓存，内存数据库开启后连接池中的所有连接访问同一个数据库，最后一个连接关闭时数据库被销毁，默认为 false。
+	JournalMode JournalMode   `json:"journalMode,omitempty"` // 日志模式，例如 WAL，默认为空，即使用 SQLite 的默认值。
+	ForeignKeys bool          `json:"foreignKeys"`           // 启用外键约束，默认为 false。
+	BusyTimeout time.Duration `json:"busyTimeout"`           // 数据库被锁定时的等待时间，默认为 0，即立即返回 SQLITE_BUSY。
+}
+
+func init() {
+	driver.Register("sqlite", func() driver.Config { return &Config{} })
+}
+
+// New 根据配置创建一个新的 Gorm 数据库连接。
+// 配置中的 PRAGMA 选项会作为 DSN 参数，在每个新建立的连接上执行。
+//
+// 参数:
+//   - conf: 数据库的配置。
+//
+// 返回值:
+//   - gorm.Dialector: 用于 Gorm 以建立数据库连接的接口。
+func New(conf Config) gorm.Dialector {
+	return &sqlite.Dialector{
+		DriverName: conf.DriverName,
+		DSN:        conf.dsn(),
+		Conn:       conf.Conn,
+	}
+}
+
+// Dialector 实现 driver.Config 接口，等同于 New(c)。
+func (c Config) Dialector() (gorm.Dialector, error) {
+	return New(c), nil
+}
+
+// Open 根据给定的 DSN (数据源名称) 打开一个 SQLite 数据库连接。
+// 它返回一个实现了 gorm.Dialector 接口的数据库连接对象，用于后续的数据库操作。
+// 该函数实际上调用了 sqlite 包中的 Open 函数来创建数据库连接。
+//
+// 参数:
+//   - dsn: 数据库文件路径或 file: URI，可以通过 _pragma 参数设置 PRAGMA，例如 test.db?_pragma=foreign_keys(1)。
+//
+// 返回值:
+//   - gorm.Dialector: 一个可以与 GORM 框架配合使用的数据库连接对象。
+func Open(dsn string) gorm.Dialector {
+	return sqlite.Open(dsn)
+}
+
+// dsn 根据配置生成驱动使用的 DSN。
+// 内存数据库与共享缓存需要使用 file: URI，PRAGMA 选项以 _pragma 参数追加在 DSN 之后。
+//
+// 返回值:
+//   - string: 驱动使用的 DSN。
+func (c Config) dsn() string {
+	name, query, _ := strings.Cut(c.DSN, "?")
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		// 无法解析的参数原样保留，交由驱动处理。
+		return c.DSN
+	}
+
+	if c.InMemory {
+		name = strings.TrimPrefix(name, "file:")
+		if name == "" || name == ":memory:" {
+			name = defaultMemoryName
+		}
+
+		name = "file:" + name
+		params.Set("mode", "memory")
+	}
+
+	if c.SharedCache {
+		// 只有 file: URI 中的 cache 参数才会被 SQLite 识别
+		if !strings.HasPrefix(name, "file:") {
+			name = "file:" + name
+		}
+
+		params.Set("cache", "shared")
+	}
+
+	if c.JournalMode != "" {
+		params.Add("_pragma", fmt.Sprintf("journal_mode(%s)", c.JournalMode))
+	}
+	if c.ForeignKeys {
+		params.Add("_pragma", "foreign_keys(1)")
+	}
+	if c.BusyTimeout > 0 {
+		params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", c.BusyTimeout.Milliseconds()))
+	}
+
+	if len(params) == 0 {
+		return name
+	}
+
+	return name + "?" + params.Encode()
+}
@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type users struct {
+	ID       uint   `gorm:"comment:用户ID;primary_key" json:"id"` //用户唯一标识
+	Username string `gorm:"comment:用户名;unique_index" json:"username"`
+}
+
+func (m users) TableName() string {
+	return "users"
+}
+
+func TestSQLite(t *testing.T) {
+	conf := Config{
+		DSN:         "sqlite_test",
+		InMemory:    true,
+		SharedCache: true,
+		ForeignKeys: true,
+		BusyTimeout: 5 * time.Second,
+	}
+	db, err := gorm.Open(New(conf), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AutoMigrate(&users{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&users{Username: "jun"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 占用建表时使用的连接，之后的查询会建立新的连接。
+	// 共享缓存下，新的连接也能看到同一个内存数据库。
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var count int64
+	if err := db.Model(users{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("got %d users, want 1", count)
+	}
+
+	var foreignKeys, busyTimeout int
+	db.Raw("PRAGMA foreign_keys").Scan(&foreignKeys)
+	db.Raw("PRAGMA busy_timeout").Scan(&busyTimeout)
+	if foreignKeys != 1 || busyTimeout != 5000 {
+		t.Fatalf("got foreign_keys=%d busy_timeout=%d", foreignKeys, busyTimeout)
+	}
+}
+
+func TestConfigDSN(t *testing.T) {
+	tests := []struct {
+		conf Config
+		want string
+	}{
+		{Config{DSN: "app.db"}, "app.db"},
+		{Config{InMemory: true, SharedCache: true}, "file:memdb?cache=shared&mode=memory"},
+		{Config{DSN: "app.db?_txlock=immediate", JournalMode: JournalModeWAL}, "app.db?_pragma=journal_mode%28WAL%29&_txlock=immediate"},
+	}
+	for _, tt := range tests {
+		if got := tt.conf.dsn(); got != tt.want {
+			t.Fatalf("got %s, want %s", got, tt.want)
+		}
+	}
+}
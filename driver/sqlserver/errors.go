@@ -0,0 +1,20 @@
+package sqlserver
+
+import (
+	"errors"
+
+	"github.com/cotton-go/pkg/ssh"
+)
+
+// 定义预定义错误，用于区分 NewWithError 失败的原因。
+// 返回的错误保留了原始错误信息，可以通过 errors.Is 判断其类型。
+var (
+	// ErrSSHDial 表示无法与 SSH 服务器建立连接。
+	ErrSSHDial = ssh.ErrDial
+
+	// ErrSSHAuth 表示 SSH 认证失败，或 SSH 认证信息无法使用。
+	ErrSSHAuth = ssh.ErrAuth
+
+	// ErrDSNParse 表示 DSN 字符串格式错误，无法解析。
+	ErrDSNParse = errors.New("unable to parse sqlserver dsn")
+)
@@ -0,0 +1,28 @@
+module github.com/cotton-go/pkg/driver/sqlserver
+
+go 1.21
+
+require (
+	github.com/cotton-go/pkg/ssh v0.0.0-20240816034421-034ecfc24f77
+	github.com/microsoft/go-mssqldb v1.6.0
+	gorm.io/driver/sqlserver v1.5.3
+	gorm.io/gorm v1.25.11
+)
+
+require gopkg.in/yaml.v3 v3.0.1 // indirect
+
+require (
+	github.com/cotton-go/pkg/driver v0.0.0
+	github.com/golang-sql/civil v0.0.0-20220223132316-b832511892a9 // indirect
+	github.com/golang-sql/sqlexp v0.1.0 // indirect
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	golang.org/x/crypto v0.26.0 // indirect
+	golang.org/x/sys v0.23.0 // indirect
+	golang.org/x/text v0.17.0 // indirect
+)
+
+replace github.com/cotton-go/pkg/ssh => ../../ssh
+
+replace github.com/cotton-go/pkg/driver => ../
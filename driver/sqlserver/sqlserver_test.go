@@ -0,0 +1,43 @@
+package sqlserver
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/cotton-go/pkg/ssh"
+)
+
+func TestNewWithError(t *testing.T) {
+	_, err := NewWithError(Config{DSN: "sqlserver://sa:pw@127.0.0.1:1433?connection+timeout=abc"})
+	if !errors.Is(err, ErrDSNParse) {
+		t.Fatalf("got %v, want ErrDSNParse", err)
+	}
+
+	_, err = NewWithError(Config{SSH: &ssh.Config{Host: "127.0.0.1"}})
+	if !errors.Is(err, ErrDSNParse) {
+		t.Fatalf("got %v, want ErrDSNParse", err)
+	}
+
+	// 同时设置 Conn 与 SSH 时返回错误，不会建立 SSH 连接，也不会覆盖调用方的连接池
+	conn := sql.OpenDB(nopConnector{})
+	defer conn.Close()
+	sshConf := &ssh.Config{Host: "127.0.0.1", Port: 1, User: "ops", Password: "secret"}
+	_, err = NewWithError(Config{DSN: "sqlserver://sa:pw@db:1433?database=app", Conn: conn, SSH: sshConf})
+	if !errors.Is(err, ErrDSNParse) {
+		t.Fatalf("got %v, want ErrDSNParse", err)
+	}
+	if _, ok := tunnels.Client(sshConf.Key()); ok {
+		t.Fatal("ssh tunnel must not be acquired when Conn is set")
+	}
+}
+
+// nopConnector 是一个不会被实际拨号的连接器，只用于创建调用方提供的 *sql.DB。
+type nopConnector struct{}
+
+func (nopConnector) Connect(context.Context) (sqldriver.Conn, error) {
+	return nil, errors.New("not implemented")
+}
+func (nopConnector) Driver() sqldriver.Driver { return nil }
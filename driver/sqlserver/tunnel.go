@@ -0,0 +1,70 @@
+package sqlserver
+
+import (
+	"context"
+	"net"
+
+	"github.com/cotton-go/pkg/ssh"
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// tunnels 记录本驱动从所有驱动共享的 SSH 连接注册表中获取的引用，相同配置的数据库共用一个 SSH 连接。
+var tunnels = ssh.DefaultRegistry.Group()
+
+// 确保 dialer 实现了 mssql.HostDialer 接口。
+var _ mssql.HostDialer = dialer{}
+
+// acquireTunnel 获取 SSH 配置对应的共享连接，并返回连接的键。
+//
+// 参数:
+//   - conf: SSH 连接配置。
+//
+// 返回值:
+//   - string: SSH 连接的键。
+//   - error: 如果建立 SSH 连接失败，则返回错误信息。
+func acquireTunnel(conf ssh.Config) (string, error) {
+	if _, err := tunnels.Acquire(conf); err != nil {
+		return "", err
+	}
+
+	return conf.Key(), nil
+}
+
+// dialer 实现 mssql.HostDialer 接口，通过共享的 SSH 连接拨号。
+// 作为 HostDialer，主机名由 SSH 服务器解析，本地不做 DNS 查询。
+type dialer struct {
+	key string // 共享 SSH 连接的键
+}
+
+// DialContext 通过 SSH 连接建立到指定地址的连接，SSH 连接断开时自动重新连接。
+func (d dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return tunnels.DialContext(ctx, d.key, network, addr)
+}
+
+// HostName 返回 SSH 连接的键，实现 mssql.HostDialer 接口。
+func (d dialer) HostName() string {
+	return d.key
+}
+
+// CloseTunnel 释放 SSH 配置对应的连接的一次引用。
+// 每次以该配置调用 New 都会增加一次引用，所有驱动的引用全部释放后关闭 SSH 连接。
+//
+// 参数:
+//   - conf: 创建数据库连接时使用的 SSH 配置。
+//
+// 返回值:
+//   - error: 如果关闭 SSH 连接失败，则返回错误信息。
+func CloseTunnel(conf ssh.Config) error {
+	_, err := tunnels.Release(conf.Key())
+	return err
+}
+
+// CloseTunnels 释放 SQL Server 驱动获取的所有 SSH 连接引用，通常在程序退出时调用。
+// 其他驱动仍在使用的 SSH 连接保持打开。
+//
+// 返回值:
+//   - error: 关闭过程中遇到的第一个错误。
+func CloseTunnels() error {
+	_, err := tunnels.Close()
+	return err
+}
@@ -0,0 +1,344 @@
+package driver
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrManagerClosed 表示 TenantManager 已经关闭。
+var ErrManagerClosed = errors.New("tenant manager is closed")
+
+// TenantResolver 返回租户的数据库配置，例如从配置中心或主库中查询租户的 DSN 与跳板机。
+// 返回的配置通常为 *mysql.Config 或 *postgres.Config，也可以通过 Decode 或 ParseURL 得到后断言为 Opener。
+type TenantResolver func(ctx context.Context, tenant string) (Opener, error)
+
+// TenantOptions 定义 TenantManager 的配置选项。
+type TenantOptions struct {
+	MaxOpen      int           // 同时打开与正在打开的租户数据库的上限，超过时关闭最久未使用的数据库，默认为 0，即不限制。
+	IdleTimeout  time.Duration // 租户数据库的最长空闲时间，超过后自动关闭，默认为 0，即不关闭。
+	DrainTimeout time.Duration // 关闭租户数据库时等待正在执行的查询结束的最长时间，默认为 DefaultDrainTimeout。
+	GormOptions  []gorm.Option // 打开数据库时传递给 gorm.Open 的选项，默认为空。
+	Logger       *slog.Logger  // 记录租户数据库打开与关闭的日志记录器，为 nil 时使用 slog.Default()。
+}
+
+// TenantManager 管理每个租户独立的数据库，可以安全地并发调用。
+// 租户的数据库在第一次使用时打开，同一租户的并发请求只会打开一次。
+// 位于同一台跳板机之后的租户由驱动共享同一个 SSH 连接，关闭最后一个租户时 SSH 连接才会关闭。
+// 关闭租户的数据库时先等待正在执行的查询结束，再释放 SSH 连接，与 Handle 相同。
+// 被关闭的租户再次使用时会重新打开，因此调用方不应长期持有 DB 返回的 *gorm.DB。
+type TenantManager struct {
+	resolve TenantResolver
+	opts    TenantOptions
+	logger  *slog.Logger
+
+	mu      sync.Mutex
+	tenants map[string]*tenantEntry
+	lru     *list.List // 已打开的租户，最近使用的在前
+	closed  bool
+
+	done    chan struct{}
+	wg      sync.WaitGroup
+	closing sync.WaitGroup // 正在打开的数据库与后台关闭被淘汰的数据库
+}
+
+// tenantEntry 记录一个租户的数据库。
+type tenantEntry struct {
+	tenant   string
+	ready    chan struct{} // 打开完成后关闭
+	opener   Opener
+	db       *gorm.DB
+	err      error
+	lastUsed time.Time
+	elem     *list.Element // 打开成功后在 lru 中的位置，正在打开时为 nil
+}
+
+// NewTenantManager 创建一个租户数据库管理器，设置了 IdleTimeout 时在后台定期关闭空闲的数据库。
+//
+// 参数:
+//   - resolve: 返回租户数据库配置的函数。
+//   - opts: 配置选项。
+//
+// 返回值:
+//   - *TenantManager: 租户数据库管理器，不再使用时需要调用 Close。
+func NewTenantManager(resolve TenantResolver, opts TenantOptions) *TenantManager {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	m := &TenantManager{
+		resolve: resolve,
+		opts:    opts,
+		logger:  logger,
+		tenants: make(map[string]*tenantEntry),
+		lru:     list.New(),
+		done:    make(chan struct{}),
+	}
+
+	if opts.IdleTimeout > 0 {
+		m.wg.Add(1)
+		go m.closeIdle()
+	}
+
+	return m
+}
+
+// DB 返回租户的数据库，尚未打开时通过 TenantResolver 获取配置并打开。
+//
+// 参数:
+//   - ctx: 上下文，取消后不再等待其他请求打开同一租户的数据库。
+//   - tenant: 租户的标识。
+//
+// 返回值:
+//   - *gorm.DB: 租户的数据库。
+//   - error: 如果获取配置或打开数据库失败，或管理器已经关闭，则返回错误信息。
+func (m *TenantManager) DB(ctx context.Context, tenant string) (*gorm.DB, error) {
+	m.mu.Lock()
+	for {
+		if m.closed {
+			m.mu.Unlock()
+			return nil, ErrManagerClosed
+		}
+
+		if e, ok := m.tenants[tenant]; ok {
+			e.lastUsed = time.Now()
+			if e.elem != nil {
+				m.lru.MoveToFront(e.elem)
+			}
+			m.mu.Unlock()
+
+			select {
+			case <-e.ready:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if e.err != nil {
+				return nil, e.err
+			}
+			return e.db, nil
+		}
+
+		if m.opts.MaxOpen <= 0 || len(m.tenants) < m.opts.MaxOpen {
+			break
+		}
+
+		// 正在打开的租户同样占用名额，优先淘汰最久未使用的已打开租户
+		if m.lru.Len() > 0 {
+			m.closeLater(m.removeLocked(m.lru.Back().Value.(*tenantEntry)), "evicted")
+			continue
+		}
+
+		// 名额全部被正在打开的租户占用，等待其中一个打开完成后重新检查
+		var pending *tenantEntry
+		for _, e := range m.tenants {
+			pending = e
+			break
+		}
+		m.mu.Unlock()
+
+		select {
+		case <-pending.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		m.mu.Lock()
+	}
+
+	e := &tenantEntry{tenant: tenant, ready: make(chan struct{}), lastUsed: time.Now()}
+	m.tenants[tenant] = e
+	// Close 等待正在打开的数据库打开完成，管理器已关闭时在返回之前关闭该数据库
+	m.closing.Add(1)
+	defer m.closing.Done()
+	m.mu.Unlock()
+
+	opener, db, err := m.open(ctx, tenant)
+
+	m.mu.Lock()
+	if err != nil {
+		delete(m.tenants, tenant)
+		e.err = err
+		close(e.ready)
+		m.mu.Unlock()
+		return nil, err
+	}
+
+	if m.closed {
+		// 打开期间管理器被关闭
+		delete(m.tenants, tenant)
+		e.opener, e.db, e.err = opener, db, ErrManagerClosed
+		close(e.ready)
+		m.mu.Unlock()
+		m.closeEntry(e, "closed")
+		return nil, ErrManagerClosed
+	}
+
+	e.opener, e.db = opener, db
+	e.elem = m.lru.PushFront(e)
+	close(e.ready)
+	m.mu.Unlock()
+
+	return db, nil
+}
+
+// Evict 关闭租户的数据库，例如租户的配置发生变化时，下次使用时重新打开。
+// 等待正在执行的查询结束后返回，租户的数据库未打开或正在打开时不做任何操作。
+//
+// 参数:
+//   - tenant: 租户的标识。
+//
+// 返回值:
+//   - error: 如果关闭数据库失败，则返回错误信息。
+func (m *TenantManager) Evict(tenant string) error {
+	m.mu.Lock()
+	e, ok := m.tenants[tenant]
+	if !ok || e.elem == nil {
+		m.mu.Unlock()
+		return nil
+	}
+	m.removeLocked(e)
+	m.mu.Unlock()
+
+	return m.closeEntry(e, "evicted")
+}
+
+// Len 返回已打开的租户数据库的数量。
+func (m *TenantManager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.lru.Len()
+}
+
+// Close 关闭所有租户的数据库并停止后台的空闲检查。
+// 所有租户同时等待正在执行的查询结束，正在打开的数据库在打开完成后关闭，Close 等待这些数据库与后台淘汰的数据库关闭完成后返回。
+//
+// 返回值:
+//   - error: 关闭过程中遇到的所有错误。
+func (m *TenantManager) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	close(m.done)
+
+	var entries []*tenantEntry
+	for m.lru.Len() > 0 {
+		entries = append(entries, m.removeLocked(m.lru.Front().Value.(*tenantEntry)))
+	}
+	m.mu.Unlock()
+
+	m.wg.Wait()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(entries))
+	for i, e := range entries {
+		wg.Add(1)
+		go func(i int, e *tenantEntry) {
+			defer wg.Done()
+			errs[i] = m.closeEntry(e, "closed")
+		}(i, e)
+	}
+	wg.Wait()
+	m.closing.Wait()
+
+	return errors.Join(errs...)
+}
+
+// open 获取租户的配置并打开数据库。
+func (m *TenantManager) open(ctx context.Context, tenant string) (Opener, *gorm.DB, error) {
+	opener, err := m.resolve(ctx, tenant)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to resolve tenant %q: %w", tenant, err)
+	}
+	if opener == nil {
+		return nil, nil, fmt.Errorf("unable to resolve tenant %q: no config", tenant)
+	}
+
+	start := time.Now()
+	db, err := opener.Open(m.opts.GormOptions...)
+	if err != nil {
+		m.logger.Error("tenant database open failed", slog.String("tenant", tenant), slog.Any("error", err))
+		return nil, nil, fmt.Errorf("unable to open tenant %q: %w", tenant, err)
+	}
+
+	m.logger.Info("tenant database opened", slog.String("tenant", tenant), slog.Duration("duration", time.Since(start)))
+	return opener, db, nil
+}
+
+// removeLocked 将已打开的租户从管理器中移除，调用方需要持有 mu。
+func (m *TenantManager) removeLocked(e *tenantEntry) *tenantEntry {
+	m.lru.Remove(e.elem)
+	e.elem = nil
+	delete(m.tenants, e.tenant)
+
+	return e
+}
+
+// closeLater 在后台关闭被淘汰的租户数据库，避免打开新租户的请求等待其他租户的查询结束，调用方需要持有 mu。
+func (m *TenantManager) closeLater(e *tenantEntry, reason string) {
+	m.closing.Add(1)
+	go func() {
+		defer m.closing.Done()
+		m.closeEntry(e, reason)
+	}()
+}
+
+// closeEntry 等待租户数据库中正在执行的查询结束后关闭数据库，reason 用于日志。
+func (m *TenantManager) closeEntry(e *tenantEntry, reason string) error {
+	timeout := m.opts.DrainTimeout
+	if timeout <= 0 {
+		timeout = DefaultDrainTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	h := &Handle{db: e.db, opener: e.opener}
+	if err := h.Shutdown(ctx); err != nil {
+		m.logger.Warn("tenant database close failed", slog.String("tenant", e.tenant), slog.String("reason", reason), slog.Any("error", err))
+		return fmt.Errorf("unable to close tenant %q: %w", e.tenant, err)
+	}
+
+	m.logger.Info("tenant database closed", slog.String("tenant", e.tenant), slog.String("reason", reason))
+	return nil
+}
+
+// closeIdle 定期关闭空闲时间超过 IdleTimeout 的数据库，直到管理器关闭。
+func (m *TenantManager) closeIdle() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.opts.IdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+		}
+
+		var idle []*tenantEntry
+		m.mu.Lock()
+		// 最久未使用的租户位于末尾
+		for m.lru.Len() > 0 {
+			e := m.lru.Back().Value.(*tenantEntry)
+			if time.Since(e.lastUsed) < m.opts.IdleTimeout {
+				break
+			}
+			idle = append(idle, m.removeLocked(e))
+		}
+		m.mu.Unlock()
+
+		for _, e := range idle {
+			m.closeEntry(e, "idle")
+		}
+	}
+}
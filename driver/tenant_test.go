@@ -0,0 +1,277 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeOpener 记录打开与关闭次数的配置，打开时使用 poolDialector。
+type fakeOpener struct {
+	opens    *atomic.Int32
+	closes   *atomic.Int32
+	inFlight *atomic.Int32 // 可选，记录正在打开的数量
+	peak     *atomic.Int32 // 可选，记录同时打开的最大数量
+	delay    time.Duration
+	err      error
+}
+
+func (o fakeOpener) Open(opts ...gorm.Option) (*gorm.DB, error) {
+	if o.inFlight != nil {
+		n := o.inFlight.Add(1)
+		defer o.inFlight.Add(-1)
+		for {
+			peak := o.peak.Load()
+			if n <= peak || o.peak.CompareAndSwap(peak, n) {
+				break
+			}
+		}
+	}
+
+	time.Sleep(o.delay)
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	o.opens.Add(1)
+	dialector := poolDialector{conn: sql.OpenDB(nopConnector{})}
+	return gorm.Open(dialector, append(opts, &gorm.Config{DryRun: true, Logger: logger.Discard})...)
+}
+
+func (o fakeOpener) Close(*gorm.DB) error {
+	o.closes.Add(1)
+	return nil
+}
+
+// newTestManager 创建一个使用 fakeOpener 的管理器。
+func newTestManager(opts TenantOptions, delay time.Duration) (*TenantManager, *atomic.Int32, *atomic.Int32) {
+	opens, closes := new(atomic.Int32), new(atomic.Int32)
+	opts.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	m := NewTenantManager(func(_ context.Context, tenant string) (Opener, error) {
+		if tenant == "missing" {
+			return nil, errors.New("no such tenant")
+		}
+		return fakeOpener{opens: opens, closes: closes, delay: delay}, nil
+	}, opts)
+
+	return m, opens, closes
+}
+
+func TestTenantManagerConcurrent(t *testing.T) {
+	m, opens, closes := newTestManager(TenantOptions{}, 20*time.Millisecond)
+
+	var wg sync.WaitGroup
+	dbs := make([]*gorm.DB, 16)
+	for i := range dbs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			db, err := m.DB(context.Background(), "a")
+			if err != nil {
+				t.Errorf("db: %v", err)
+			}
+			dbs[i] = db
+		}(i)
+	}
+	wg.Wait()
+
+	if got := opens.Load(); got != 1 {
+		t.Fatalf("opens = %d, want 1", got)
+	}
+	for _, db := range dbs {
+		if db != dbs[0] {
+			t.Fatal("concurrent callers got different databases")
+		}
+	}
+
+	if _, err := m.DB(context.Background(), "missing"); err == nil {
+		t.Fatal("expected resolve error")
+	}
+	if m.Len() != 1 {
+		t.Fatalf("len = %d, want 1", m.Len())
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if got := closes.Load(); got != 1 {
+		t.Fatalf("closes = %d, want 1", got)
+	}
+	if _, err := m.DB(context.Background(), "a"); !errors.Is(err, ErrManagerClosed) {
+		t.Fatalf("db after close error = %v, want ErrManagerClosed", err)
+	}
+}
+
+func TestTenantManagerLRU(t *testing.T) {
+	ctx := context.Background()
+	m, opens, closes := newTestManager(TenantOptions{MaxOpen: 2}, 0)
+	defer m.Close()
+
+	for _, tenant := range []string{"a", "b", "a", "c"} {
+		if _, err := m.DB(ctx, tenant); err != nil {
+			t.Fatalf("db %s: %v", tenant, err)
+		}
+	}
+	// b 最久未使用，打开 c 时在后台被关闭
+	waitFor(t, func() bool { return closes.Load() == 1 })
+	if m.Len() != 2 || opens.Load() != 3 || closes.Load() != 1 {
+		t.Fatalf("len = %d, opens = %d, closes = %d, want 2, 3, 1", m.Len(), opens.Load(), closes.Load())
+	}
+
+	if _, err := m.DB(ctx, "a"); err != nil || opens.Load() != 3 {
+		t.Fatalf("a was reopened: opens = %d, err = %v", opens.Load(), err)
+	}
+	if _, err := m.DB(ctx, "b"); err != nil || opens.Load() != 4 {
+		t.Fatalf("b was not reopened: opens = %d, err = %v", opens.Load(), err)
+	}
+
+	if err := m.Evict("a"); err != nil || m.Len() != 1 {
+		t.Fatalf("evict: len = %d, err = %v", m.Len(), err)
+	}
+}
+
+func TestTenantManagerIdle(t *testing.T) {
+	m, _, closes := newTestManager(TenantOptions{IdleTimeout: 40 * time.Millisecond}, 0)
+	defer m.Close()
+
+	if _, err := m.DB(context.Background(), "a"); err != nil {
+		t.Fatalf("db: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for m.Len() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if m.Len() != 0 || closes.Load() != 1 {
+		t.Fatalf("len = %d, closes = %d, want idle tenant closed", m.Len(), closes.Load())
+	}
+}
+
+// waitFor 等待 cond 成立，最多等待一秒。
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestTenantManagerDrain(t *testing.T) {
+	ctx := context.Background()
+	m, _, closes := newTestManager(TenantOptions{MaxOpen: 1}, 0)
+	defer m.Close()
+
+	db, err := m.DB(ctx, "a")
+	if err != nil {
+		t.Fatalf("db: %v", err)
+	}
+	sqlDB, _ := db.DB()
+
+	// 模拟 a 上正在执行的查询
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+
+	// 打开 b 时淘汰 a，但不会等待 a 的查询结束
+	if _, err := m.DB(ctx, "b"); err != nil {
+		t.Fatalf("db: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if closes.Load() != 0 {
+		t.Fatal("tenant closed while a query was in use")
+	}
+
+	conn.Close()
+	waitFor(t, func() bool { return closes.Load() == 1 })
+}
+
+func TestTenantManagerMaxOpenPending(t *testing.T) {
+	opens, closes, inFlight, peak := new(atomic.Int32), new(atomic.Int32), new(atomic.Int32), new(atomic.Int32)
+	m := NewTenantManager(func(context.Context, string) (Opener, error) {
+		return fakeOpener{opens: opens, closes: closes, inFlight: inFlight, peak: peak, delay: 20 * time.Millisecond}, nil
+	}, TenantOptions{MaxOpen: 2, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+
+	var wg sync.WaitGroup
+	for _, tenant := range []string{"a", "b", "c", "d", "e", "f"} {
+		wg.Add(1)
+		go func(tenant string) {
+			defer wg.Done()
+			if _, err := m.DB(context.Background(), tenant); err != nil {
+				t.Errorf("db %s: %v", tenant, err)
+			}
+		}(tenant)
+	}
+	wg.Wait()
+
+	// 正在打开的租户同样计入上限
+	if got := peak.Load(); got > 2 {
+		t.Fatalf("peak concurrent opens = %d, want at most 2", got)
+	}
+	if m.Len() > 2 {
+		t.Fatalf("len = %d, want at most 2", m.Len())
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if opens.Load() != closes.Load() {
+		t.Fatalf("opens = %d, closes = %d, want every database closed", opens.Load(), closes.Load())
+	}
+
+	// 等待名额时上下文取消
+	m2, _, _ := newTestManager(TenantOptions{MaxOpen: 1}, 200*time.Millisecond)
+	defer m2.Close()
+	go m2.DB(context.Background(), "a")
+	waitFor(t, func() bool {
+		m2.mu.Lock()
+		defer m2.mu.Unlock()
+		return len(m2.tenants) == 1
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := m2.DB(ctx, "b"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestTenantManagerClosePending(t *testing.T) {
+	m, opens, closes := newTestManager(TenantOptions{}, 100*time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := m.DB(context.Background(), "a")
+		done <- err
+	}()
+	waitFor(t, func() bool {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return len(m.tenants) == 1
+	})
+
+	// Close 等待正在打开的租户打开完成并关闭后返回
+	if err := m.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if opens.Load() != 1 || closes.Load() != 1 {
+		t.Fatalf("opens = %d, closes = %d, want 1, 1", opens.Load(), closes.Load())
+	}
+	if err := <-done; !errors.Is(err, ErrManagerClosed) {
+		t.Fatalf("got %v, want ErrManagerClosed", err)
+	}
+}
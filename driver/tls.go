@@ -0,0 +1,80 @@
+package driver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ReadPEM 读取 PEM 格式的证书或私钥，参数以 "-----BEGIN " 开头时视为内联的 PEM 内容，否则视为文件路径。
+//
+// 参数:
+//   - s: 文件路径或 PEM 内容。
+//
+// 返回值:
+//   - []byte: PEM 内容。
+//   - error: 如果读取文件失败，则返回错误信息。
+func ReadPEM(s string) ([]byte, error) {
+	if strings.HasPrefix(strings.TrimSpace(s), "-----BEGIN ") {
+		return []byte(s), nil
+	}
+
+	data, err := os.ReadFile(s)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read pem file: %w", err)
+	}
+
+	return data, nil
+}
+
+// CertPool 根据 CA 证书创建证书池。
+//
+// 参数:
+//   - ca: CA 证书的文件路径或 PEM 内容。
+//
+// 返回值:
+//   - *x509.CertPool: 包含 CA 证书的证书池。
+//   - error: 如果读取或解析证书失败，则返回错误信息。
+func CertPool(ca string) (*x509.CertPool, error) {
+	data, err := ReadPEM(ca)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("unable to add CA certificate to cert pool")
+	}
+
+	return pool, nil
+}
+
+// KeyPair 根据客户端证书与私钥创建 TLS 证书。
+//
+// 参数:
+//   - cert: 客户端证书的文件路径或 PEM 内容。
+//   - key: 客户端私钥的文件路径或 PEM 内容。
+//
+// 返回值:
+//   - tls.Certificate: 客户端证书。
+//   - error: 如果读取或解析失败，则返回错误信息。
+func KeyPair(cert, key string) (tls.Certificate, error) {
+	certPEM, err := ReadPEM(cert)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPEM, err := ReadPEM(key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("unable to load client certificate: %w", err)
+	}
+
+	return pair, nil
+}
@@ -0,0 +1,196 @@
+package ssh
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// asciicast v2 的事件类型
+const (
+	AsciicastOutput = "o" // 终端输出
+	AsciicastInput  = "i" // 终端输入
+	AsciicastMarker = "m" // 标记
+	AsciicastResize = "r" // 终端尺寸变化
+)
+
+// AsciicastHeader 定义 asciicast v2 文件头。
+// 除规范中的字段外，还记录了 SSH 会话的用户与主机，播放器会忽略这些额外字段。
+type AsciicastHeader struct {
+	Version   int               `json:"version"`             // 文件格式版本，固定为 2
+	Width     int               `json:"width"`               // 终端宽度
+	Height    int               `json:"height"`              // 终端高度
+	Timestamp int64             `json:"timestamp,omitempty"` // 录制开始的 Unix 时间戳
+	Command   string            `json:"command,omitempty"`   // 执行的命令，交互式会话为空
+	Title     string            `json:"title,omitempty"`     // 录制标题
+	Env       map[string]string `json:"env,omitempty"`       // 录制时的环境变量，例如 TERM
+	User      string            `json:"user,omitempty"`      // SSH 登录用户名
+	Host      string            `json:"host,omitempty"`      // SSH 远程主机地址
+}
+
+// AsciicastWriter 将终端事件以 asciicast v2 格式写入 io.Writer，可以安全地并发使用。
+type AsciicastWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	start   time.Time
+	pending map[string][]byte // 每种事件尚未写出的不完整 UTF-8 字节
+	closed  bool              // 是否已经结束录制
+}
+
+// NewAsciicastWriter 创建一个 AsciicastWriter 并立即写入文件头。
+//
+// 参数:
+//   - w: 录制内容的输出，例如打开的 .cast 文件。
+//   - header: 文件头信息，Version、Width、Height、Timestamp 为空时会使用默认值。
+//
+// 返回值:
+//   - *AsciicastWriter: 创建的录制写入器。
+//   - error: 如果写入文件头失败，则返回错误信息。
+func NewAsciicastWriter(w io.Writer, header AsciicastHeader) (*AsciicastWriter, error) {
+	start := time.Now()
+	header.Version = 2
+	if header.Width == 0 {
+		header.Width = 80
+	}
+	if header.Height == 0 {
+		header.Height = 24
+	}
+	if header.Timestamp == 0 {
+		header.Timestamp = start.Unix()
+	}
+
+	line, err := json.Marshal(header)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to marshal asciicast header")
+	}
+
+	if _, err := w.Write(append(line, '\n')); err != nil {
+		return nil, errors.Wrap(err, "unable to write asciicast header")
+	}
+
+	return &AsciicastWriter{w: w, start: start, pending: make(map[string][]byte)}, nil
+}
+
+// WriteEvent 写入一个事件，时间为相对录制开始的秒数。
+// 末尾不完整的 UTF-8 字符会保留到下一次同类事件中一并写出。
+//
+// 参数:
+//   - kind: 事件类型，例如 AsciicastOutput、AsciicastInput。
+//   - data: 事件数据。
+//
+// 返回值:
+//   - error: 如果写入失败，则返回错误信息。
+func (a *AsciicastWriter) WriteEvent(kind string, data []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// 结束录制后的事件直接忽略
+	if a.closed {
+		return nil
+	}
+
+	data = append(a.pending[kind], data...)
+	cut := incompleteRuneStart(data)
+	a.pending[kind] = append([]byte(nil), data[cut:]...)
+
+	return a.write(kind, string(data[:cut]))
+}
+
+// Output 返回一个将写入内容记录为输出事件的 io.Writer。
+func (a *AsciicastWriter) Output() io.Writer {
+	return asciicastEventWriter{a: a, kind: AsciicastOutput}
+}
+
+// Input 返回一个将写入内容记录为输入事件的 io.Writer。
+func (a *AsciicastWriter) Input() io.Writer {
+	return asciicastEventWriter{a: a, kind: AsciicastInput}
+}
+
+// Resize 记录终端尺寸变化。
+func (a *AsciicastWriter) Resize(width, height int) error {
+	return a.WriteEvent(AsciicastResize, []byte(strconv.Itoa(width)+"x"+strconv.Itoa(height)))
+}
+
+// Close 写出剩余的数据，并以 "exit:<code>" 标记事件记录退出码。
+// 它不会关闭底层的 io.Writer，重复调用时不做任何操作。
+//
+// 参数:
+//   - exitCode: 会话的退出码，未知时为 -1。
+//
+// 返回值:
+//   - error: 如果写入失败，则返回错误信息。
+func (a *AsciicastWriter) Close(exitCode int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return nil
+	}
+	a.closed = true
+
+	for kind, data := range a.pending {
+		if len(data) == 0 {
+			continue
+		}
+
+		if err := a.write(kind, string(data)); err != nil {
+			return err
+		}
+		delete(a.pending, kind)
+	}
+
+	return a.write(AsciicastMarker, "exit:"+strconv.Itoa(exitCode))
+}
+
+// write 写入一行事件，调用方需要持有锁。
+func (a *AsciicastWriter) write(kind, data string) error {
+	if data == "" {
+		return nil
+	}
+
+	elapsed := time.Since(a.start).Seconds()
+	line, err := json.Marshal([]any{elapsed, kind, data})
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal asciicast event")
+	}
+
+	if _, err := a.w.Write(append(line, '\n')); err != nil {
+		return errors.Wrap(err, "unable to write asciicast event")
+	}
+
+	return nil
+}
+
+// asciicastEventWriter 将写入内容记录为指定类型的事件。
+type asciicastEventWriter struct {
+	a    *AsciicastWriter
+	kind string
+}
+
+// Write 实现 io.Writer 接口。
+func (w asciicastEventWriter) Write(p []byte) (int, error) {
+	if err := w.a.WriteEvent(w.kind, p); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// incompleteRuneStart 返回 data 末尾不完整 UTF-8 字符的起始位置，没有不完整字符时返回 len(data)。
+func incompleteRuneStart(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			break
+		}
+	}
+
+	return len(data)
+}
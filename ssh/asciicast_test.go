@@ -0,0 +1,60 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestAsciicastWriter(t *testing.T) {
+	var buf bytes.Buffer
+	cast, err := NewAsciicastWriter(&buf, AsciicastHeader{Command: "uptime", User: "ops", Host: "bastion:22"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "你" 被拆分到两次写入中，应合并为一个完整字符输出
+	word := []byte("你")
+	if _, err := cast.Output().Write(append([]byte("hi "), word[:1]...)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cast.Output().Write(word[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := cast.Close(3); err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := bufio.NewScanner(&buf)
+	if !scanner.Scan() {
+		t.Fatal("missing header")
+	}
+
+	var header AsciicastHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		t.Fatal(err)
+	}
+	if header.Version != 2 || header.Width != 80 || header.Command != "uptime" || header.User != "ops" {
+		t.Fatalf("unexpected header %+v", header)
+	}
+
+	var data []string
+	for scanner.Scan() {
+		var event []any
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, event[1].(string)+":"+event[2].(string))
+	}
+
+	want := []string{"o:hi ", "o:你", "m:exit:3"}
+	if len(data) != len(want) {
+		t.Fatalf("got events %q, want %q", data, want)
+	}
+	for i := range want {
+		if data[i] != want[i] {
+			t.Fatalf("got events %q, want %q", data, want)
+		}
+	}
+}
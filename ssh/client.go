@@ -1,14 +1,28 @@
 package ssh
 
 import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
 	"golang.org/x/crypto/ssh"
 )
 
 // Client 结构体代表一个SSH客户端连接，它包含了一个指向ssh.Client的指针。
 type Client struct {
-	conn *ssh.Client // 指向ssh.Client的指针，表示SSH客户端连接
+	conn   *ssh.Client   // 指向ssh.Client的指针，表示SSH客户端连接
+	logger *slog.Logger  // 用于记录连接事件的日志记录器，可能为 nil
+	done   chan struct{} // 底层连接断开后关闭，可能为 nil
 }
 
+// aliveTimeout 是 Alive 等待服务器响应 keepalive 请求的最长时间。
+const aliveTimeout = 5 * time.Second
+
 // Client 方法返回当前客户端的 SSH 连接。
 //
 // 返回值是
@@ -16,3 +30,150 @@ type Client struct {
 func (c Client) Client() *ssh.Client {
 	return c.conn
 }
+
+// Close 关闭 SSH 连接，通过该连接转发的所有通道都会随之关闭。
+//
+// 返回值:
+//   - error: 如果关闭失败，则返回错误信息。
+func (c Client) Close() error {
+	if err := c.conn.Close(); err != nil {
+		return errors.Wrap(err, "failed to close ssh connection")
+	}
+
+	c.log().Info("ssh closed")
+	return nil
+}
+
+// Alive 判断 SSH 连接是否仍然可用。
+// 底层连接已经断开时直接返回 false，否则发送一个 keepalive 请求，服务器在 5 秒内没有响应时同样视为不可用，
+// 这样可以发现网络中断后没有收到 RST 的半开连接。
+//
+// 参数:
+//   - ctx: 上下文，取消后不再等待服务器响应，此时视为连接可用。
+//
+// 返回值:
+//   - bool: 连接是否可用。
+func (c Client) Alive(ctx context.Context) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		// 服务器拒绝该请求时同样会回复，只有连接不可用时才会返回错误
+		_, _, err := c.conn.SendRequest("keepalive@openssh.com", true, nil)
+		result <- err
+	}()
+
+	timer := time.NewTimer(aliveTimeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-result:
+		return err == nil
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return true
+	}
+}
+
+// Dial 通过 SSH 连接打开一个到远程网络地址的转发通道。
+//
+// 参数:
+//   - network: 网络类型，例如 "tcp"、"unix" 等。
+//   - address: 要连接的网络地址。
+//
+// 返回值:
+//   - net.Conn: 建立的网络连接。
+//   - error: 如果连接失败，则返回错误信息。
+func (c Client) Dial(network, address string) (net.Conn, error) {
+	return c.DialContext(context.Background(), network, address)
+}
+
+// DialContext 与 Dial 相同，但支持通过 ctx 取消连接过程。
+// 每次拨号都会记录目标地址与耗时。
+//
+// 参数:
+//   - ctx: 用于控制连接超时或取消的上下文。
+//   - network: 网络类型，例如 "tcp"、"unix" 等。
+//   - address: 要连接的网络地址。
+//
+// 返回值:
+//   - net.Conn: 建立的网络连接。
+//   - error: 如果连接失败，则返回错误信息。
+func (c Client) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	start := time.Now()
+	conn, err := c.conn.DialContext(ctx, network, address)
+	logDial(c.log(), network, address, start, err)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to dial %s %s", network, address)
+	}
+
+	return conn, nil
+}
+
+// DialUnix 通过 SSH 连接打开一个到远程主机上 Unix 域套接字的连接。
+// 它使用 OpenSSH 的 direct-streamlocal@openssh.com 通道，
+// 适用于只监听 /var/run/mysqld/mysqld.sock 之类套接字文件的服务。
+//
+// 参数:
+//   - socketPath: 远程主机上 Unix 域套接字文件的路径。
+//
+// 返回值:
+//   - net.Conn: 建立的网络连接。
+//   - error: 如果连接失败，则返回错误信息。
+func (c Client) DialUnix(socketPath string) (net.Conn, error) {
+	return c.DialUnixContext(context.Background(), socketPath)
+}
+
+// DialUnixContext 与 DialUnix 相同，但支持通过 ctx 取消连接过程。
+//
+// 参数:
+//   - ctx: 用于控制连接超时或取消的上下文。
+//   - socketPath: 远程主机上 Unix 域套接字文件的路径。
+//
+// 返回值:
+//   - net.Conn: 建立的网络连接。
+//   - error: 如果连接失败，则返回错误信息。
+func (c Client) DialUnixContext(ctx context.Context, socketPath string) (net.Conn, error) {
+	return c.DialContext(ctx, "unix", socketPath)
+}
+
+// run 在新的 SSH 会话中执行命令并返回其标准输出。
+//
+// 参数:
+//   - command: 在远程主机上执行的命令。
+//   - stdin: 命令的标准输入，可以为 nil。
+//
+// 返回值:
+//   - []byte: 命令的标准输出。
+//   - error: 如果会话创建失败或命令以非零状态退出，则返回错误信息。
+func (c Client) run(command string, stdin io.Reader) ([]byte, error) {
+	session, err := c.conn.NewSession()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create ssh session")
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdin = stdin
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	if err := session.Run(command); err != nil {
+		return nil, errors.Wrapf(err, "remote command failed: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// log 返回客户端的日志记录器，未配置时返回一个丢弃所有日志的记录器。
+func (c Client) log() *slog.Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+
+	return Config{}.logger()
+}
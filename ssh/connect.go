@@ -2,7 +2,10 @@ package ssh
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/ssh"
@@ -20,22 +23,55 @@ const (
 	ConfigTypeByPrivateKeyPath
 )
 
+// MarshalText 实现 encoding.TextMarshaler 接口，将认证类型编码为 String 返回的名称，例如 "password"。
+func (t ConfigType) MarshalText() ([]byte, error) {
+	if t > ConfigTypeByPrivateKeyPath {
+		return nil, errors.Errorf("unknown ssh auth type %d", t)
+	}
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler 接口。
+// 接受 String 返回的名称，例如 "password"、"private_key"、"private_key_path"，也接受 "0" 这样的数字以兼容旧的配置。
+func (t *ConfigType) UnmarshalText(text []byte) error {
+	for _, typ := range []ConfigType{ConfigTypeByPassword, ConfigTypeByPrivateKey, ConfigTypeByPrivateKeyPath} {
+		if string(text) == typ.String() || string(text) == strconv.Itoa(int(typ)) {
+			*t = typ
+			return nil
+		}
+	}
+	return errors.Errorf("unknown ssh auth type %q", text)
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler 接口，同时接受字符串名称与旧配置中的数字，null 保持原值不变。
+func (t *ConfigType) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if text, err := strconv.Unquote(string(data)); err == nil {
+		return t.UnmarshalText([]byte(text))
+	}
+	return t.UnmarshalText(data)
+}
+
 // Config SSH连接配置信息结构体
 type Config struct {
 	// Host SSH远程主机的IP地址或域名
-	Host string
+	Host string `json:"host,omitempty"`
 	// Port SSH远程主机的连接端口
-	Port int
+	Port int `json:"port,omitempty"`
 	// Type SSH连接的认证类型，包括密码、私钥内容或私钥文件路径三种方式
-	Type ConfigType
+	Type ConfigType `json:"type,omitempty"`
 	// User SSH远程主机的登录用户名
-	User string
+	User string `json:"user,omitempty"`
 	// Password SSH远程主机的登录密码，仅在Type为ConfigTypeByPassword时生效
-	Password string
+	Password string `json:"password,omitempty"`
 	// PrivateKey SSH远程主机的私钥内容，仅在Type为ConfigTypeByPrivateKey时生效
-	PrivateKey string
+	PrivateKey string `json:"privateKey,omitempty"`
 	// PrivateKeyPath SSH远程主机的私钥文件路径，仅在Type为ConfigTypeByPrivateKeyPath时生效
-	PrivateKeyPath string
+	PrivateKeyPath string `json:"privateKeyPath,omitempty"`
+	// Logger 用于记录连接、认证、主机公钥指纹以及转发拨号等事件的日志记录器，为 nil 时不输出日志
+	Logger *slog.Logger `json:"-"`
 }
 
 // Connect 根据提供的配置信息创建一个SSH客户端连接。
@@ -45,29 +81,55 @@ type Config struct {
 //   - config: 包含了SSH连接所需的配置信息，包括用户类型、密码、私钥等。
 //
 // 返回值:
-//   - *ssh.ClientConfig 类型的SSH客户端配置指针，以及可能的错误信息。
+//   - *Client 类型的SSH客户端指针，以及可能的错误信息。
+//     错误可以通过 errors.Is 与 ErrDial、ErrAuth 比较以区分失败原因。
 func Connect(conf Config) (*Client, error) {
 	// 如果未指定端口号，则使用默认的SSH端口
 	if conf.Port == 0 {
 		conf.Port = 22
 	}
 
+	// 为本次连接的所有日志事件附加主机与用户信息
+	addr := fmt.Sprintf("%s:%d", conf.Host, conf.Port)
+	logger := conf.logger().With(slog.String(LogKeyHost, addr), slog.String(LogKeyUser, conf.User))
+	start := time.Now()
+
 	// 根据配置信息创建SSH客户端配置
 	clientConfig, err := NewSSHConfig(conf)
 	if err != nil {
 		// 如果创建客户端配置失败，返回错误
-		return nil, err
+		logger.Error("ssh config invalid", slog.String(LogKeyAuth, conf.Type.String()), slog.Any("error", err))
+		return nil, &connectError{kind: ErrAuth, err: err}
 	}
 
+	// 在校验主机公钥时记录其指纹
+	clientConfig.HostKeyCallback = logHostKey(logger, clientConfig.HostKeyCallback)
+
 	// 使用TCP协议拨号连接到SSH服务器
-	conn, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", conf.Host, conf.Port), clientConfig)
+	conn, err := ssh.Dial("tcp", addr, clientConfig)
 	if err != nil {
 		// 如果连接失败，包装原始错误并返回
-		return nil, errors.Wrap(err, "failed to connect to SSH server")
+		logger.Error("ssh connect failed",
+			slog.String(LogKeyAuth, conf.Type.String()),
+			slog.Duration(LogKeyDuration, time.Since(start)),
+			slog.Any("error", err),
+		)
+		return nil, classifyDialError(errors.Wrap(err, "failed to connect to SSH server"))
 	}
 
-	// 连接成功，返回SSH客户端实例
-	return &Client{conn}, nil
+	logger.Info("ssh connected",
+		slog.String(LogKeyAuth, conf.Type.String()),
+		slog.Duration(LogKeyDuration, time.Since(start)),
+	)
+
+	// 连接成功，返回SSH客户端实例，并在后台等待连接断开
+	client := &Client{conn: conn, logger: logger, done: make(chan struct{})}
+	go func() {
+		conn.Wait()
+		close(client.done)
+	}()
+
+	return client, nil
 }
 
 // NewSSHConfig 根据提供的配置生成SSH客户端配置。
@@ -0,0 +1,60 @@
+package ssh
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestConnectErrors(t *testing.T) {
+	// 监听后立即关闭，得到一个不可连接的本地端口
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	_, err = Connect(Config{Host: "127.0.0.1", Port: port, User: "ops", Password: "secret"})
+	if !errors.Is(err, ErrDial) {
+		t.Fatalf("got %v, want ErrDial", err)
+	}
+
+	_, err = Connect(Config{Host: "127.0.0.1", Port: port, User: "ops", Type: ConfigTypeByPrivateKey, PrivateKey: "invalid"})
+	if !errors.Is(err, ErrAuth) {
+		t.Fatalf("got %v, want ErrAuth", err)
+	}
+}
+
+func TestConfigTypeJSON(t *testing.T) {
+	for _, tt := range []struct {
+		raw  string
+		want ConfigType
+	}{
+		{`"password"`, ConfigTypeByPassword},
+		{`"private_key"`, ConfigTypeByPrivateKey},
+		{`"private_key_path"`, ConfigTypeByPrivateKeyPath},
+		{`2`, ConfigTypeByPrivateKeyPath},
+	} {
+		var got ConfigType
+		if err := json.Unmarshal([]byte(tt.raw), &got); err != nil || got != tt.want {
+			t.Fatalf("%s: got %v, %v, want %v", tt.raw, got, err, tt.want)
+		}
+	}
+
+	for _, raw := range []string{`"certificate"`, `3`, `true`} {
+		var got ConfigType
+		if err := json.Unmarshal([]byte(raw), &got); err == nil {
+			t.Fatalf("%s: expected error", raw)
+		}
+	}
+
+	data, err := json.Marshal(Config{Host: "bastion", Type: ConfigTypeByPrivateKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"host":"bastion","type":"private_key"}`; string(data) != want {
+		t.Fatalf("got %s, want %s", data, want)
+	}
+}
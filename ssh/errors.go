@@ -0,0 +1,51 @@
+package ssh
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// 定义预定义错误，用于标识 SSH 连接失败的原因。
+// Connect 返回的错误保留了原始错误信息，可以通过 errors.Is 判断其类型。
+var (
+	// ErrDial 表示无法与 SSH 服务器建立连接或完成握手，例如网络不可达、端口未开放。
+	ErrDial = errors.New("ssh dial failed")
+
+	// ErrAuth 表示认证失败，包括密码或私钥被服务器拒绝，以及私钥无法读取或解析。
+	ErrAuth = errors.New("ssh authentication failed")
+
+	// ErrTunnelClosed 表示 SSH 连接已经关闭，无法再通过它转发连接。
+	ErrTunnelClosed = errors.New("ssh tunnel closed")
+)
+
+// connectError 将连接过程中的错误归类为预定义错误，同时保留原始错误。
+type connectError struct {
+	kind error // 预定义错误类型
+	err  error // 原始错误
+}
+
+// Error 实现 error 接口，返回原始错误信息。
+func (e *connectError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap 返回原始错误，以便使用 errors.As 获取底层错误。
+func (e *connectError) Unwrap() error {
+	return e.err
+}
+
+// Is 判断错误是否属于指定的预定义错误类型。
+func (e *connectError) Is(target error) bool {
+	return target == e.kind
+}
+
+// classifyDialError 根据 ssh.Dial 返回的错误判断是认证失败还是连接失败。
+func classifyDialError(err error) error {
+	kind := ErrDial
+	if strings.Contains(err.Error(), "unable to authenticate") {
+		kind = ErrAuth
+	}
+
+	return &connectError{kind: kind, err: err}
+}
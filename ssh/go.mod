@@ -1,6 +1,6 @@
 module github.com/cotton-go/pkg/ssh
 
-go 1.17
+go 1.21
 
 require (
 	github.com/lib/pq v1.10.9
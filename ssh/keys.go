@@ -0,0 +1,255 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// KeyType 密钥对支持的算法类型
+type KeyType uint8
+
+const (
+	// KeyTypeEd25519 表示 ed25519 算法，推荐使用
+	KeyTypeEd25519 KeyType = iota
+	// KeyTypeRSA 表示 RSA 算法
+	KeyTypeRSA
+	// KeyTypeECDSA 表示 ECDSA 算法
+	KeyTypeECDSA
+)
+
+const (
+	// DefaultRSABits 生成 RSA 密钥时的默认位数
+	DefaultRSABits = 4096
+	// DefaultECDSABits 生成 ECDSA 密钥时的默认曲线位数
+	DefaultECDSABits = 256
+)
+
+// authorizedKeysPath 远程主机上当前用户的 authorized_keys 文件路径
+const authorizedKeysPath = "~/.ssh/authorized_keys"
+
+// KeyPair 结构体代表一个 SSH 密钥对。
+type KeyPair struct {
+	PrivateKey crypto.Signer // 私钥
+	PublicKey  ssh.PublicKey // SSH 格式的公钥
+}
+
+// GenerateKey 生成一个新的 SSH 密钥对。
+//
+// 参数:
+//   - keyType: 密钥算法类型。
+//   - bits: 密钥位数，RSA 默认为 4096，ECDSA 支持 256、384、521，默认为 256，ed25519 忽略该参数。
+//
+// 返回值:
+//   - *KeyPair: 生成的密钥对。
+//   - error: 如果生成失败，则返回错误信息。
+func GenerateKey(keyType KeyType, bits int) (*KeyPair, error) {
+	var (
+		signer crypto.Signer
+		err    error
+	)
+
+	switch keyType {
+	case KeyTypeEd25519:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	case KeyTypeRSA:
+		if bits == 0 {
+			bits = DefaultRSABits
+		}
+
+		signer, err = rsa.GenerateKey(rand.Reader, bits)
+	case KeyTypeECDSA:
+		var curve elliptic.Curve
+		switch bits {
+		case 0, 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		case 521:
+			curve = elliptic.P521()
+		default:
+			return nil, errors.Errorf("unsupported ecdsa key size %d", bits)
+		}
+
+		signer, err = ecdsa.GenerateKey(curve, rand.Reader)
+	default:
+		return nil, errors.Errorf("unsupported key type %d", keyType)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to generate private key")
+	}
+
+	publicKey, err := ssh.NewPublicKey(signer.Public())
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create public key")
+	}
+
+	return &KeyPair{PrivateKey: signer, PublicKey: publicKey}, nil
+}
+
+// MarshalPrivateKey 将私钥编码为 OpenSSH 格式的 PEM 内容，可直接写入 id_ed25519 等私钥文件。
+//
+// 参数:
+//   - comment: 写入私钥中的注释，通常为 user@host。
+//   - passphrase: 私钥的加密口令，为空时不加密。
+//
+// 返回值:
+//   - []byte: PEM 编码的私钥内容。
+//   - error: 如果编码失败，则返回错误信息。
+func (k *KeyPair) MarshalPrivateKey(comment string, passphrase []byte) ([]byte, error) {
+	var (
+		block *pem.Block
+		err   error
+	)
+
+	if len(passphrase) > 0 {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(k.PrivateKey, comment, passphrase)
+	} else {
+		block, err = ssh.MarshalPrivateKey(k.PrivateKey, comment)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to marshal private key")
+	}
+
+	return pem.EncodeToMemory(block), nil
+}
+
+// AuthorizedKey 返回公钥在 authorized_keys 文件中的一行内容，不包含结尾的换行符。
+//
+// 参数:
+//   - comment: 追加在公钥后的注释，为空时不追加。
+//
+// 返回值:
+//   - string: authorized_keys 格式的公钥内容。
+func (k *KeyPair) AuthorizedKey(comment string) string {
+	return authorizedKeyLine(k.PublicKey, comment)
+}
+
+// Fingerprint 返回公钥的 SHA256 指纹，格式与 ssh-keygen -l 一致。
+func (k *KeyPair) Fingerprint() string {
+	return ssh.FingerprintSHA256(k.PublicKey)
+}
+
+// AddAuthorizedKey 将公钥添加到远程主机上当前登录用户的 ~/.ssh/authorized_keys 文件中。
+// 如果公钥已经存在（忽略选项与注释的差异），则不做任何修改。
+// 先读取整个文件再写回，没有加锁，并发修改同一用户的 authorized_keys 时其中一次修改可能会丢失。
+//
+// 参数:
+//   - key: 要添加的公钥。
+//   - comment: 追加在公钥后的注释，为空时不追加。
+//
+// 返回值:
+//   - error: 如果读取或写入远程文件失败，则返回错误信息。
+func (c Client) AddAuthorizedKey(key ssh.PublicKey, comment string) error {
+	lines, err := c.readAuthorizedKeys()
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		if matchAuthorizedKey(line, key) {
+			// 公钥已存在，无需写入
+			return nil
+		}
+	}
+
+	return c.writeAuthorizedKeys(append(lines, authorizedKeyLine(key, comment)))
+}
+
+// RemoveAuthorizedKey 从远程主机上当前登录用户的 ~/.ssh/authorized_keys 文件中删除公钥。
+// 如果公钥不存在，则不做任何修改。
+// 与 AddAuthorizedKey 相同，没有加锁，并发修改同一用户的 authorized_keys 时其中一次修改可能会丢失。
+//
+// 参数:
+//   - key: 要删除的公钥。
+//
+// 返回值:
+//   - error: 如果读取或写入远程文件失败，则返回错误信息。
+func (c Client) RemoveAuthorizedKey(key ssh.PublicKey) error {
+	lines, err := c.readAuthorizedKeys()
+	if err != nil {
+		return err
+	}
+
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if !matchAuthorizedKey(line, key) {
+			kept = append(kept, line)
+		}
+	}
+
+	if len(kept) == len(lines) {
+		// 公钥不存在，无需写入
+		return nil
+	}
+
+	return c.writeAuthorizedKeys(kept)
+}
+
+// readAuthorizedKeys 读取远程 authorized_keys 文件的全部行，文件不存在时返回空列表。
+// 文件存在但无法读取时返回错误，避免随后的写入覆盖原有的公钥。
+func (c Client) readAuthorizedKeys() ([]string, error) {
+	out, err := c.run("test -e "+authorizedKeysPath+" || exit 0; cat "+authorizedKeysPath, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read authorized_keys")
+	}
+
+	content := strings.TrimRight(string(out), "\n")
+	if content == "" {
+		return nil, nil
+	}
+
+	return strings.Split(content, "\n"), nil
+}
+
+// writeAuthorizedKeys 以原子替换的方式写入远程 authorized_keys 文件，
+// 已存在的 ~/.ssh 目录同样修改为 0700，新写入的文件权限为 0600。
+func (c Client) writeAuthorizedKeys(lines []string) error {
+	var content bytes.Buffer
+	for _, line := range lines {
+		content.WriteString(line)
+		content.WriteByte('\n')
+	}
+
+	command := "umask 077 && mkdir -p ~/.ssh && chmod 700 ~/.ssh && cat > " + authorizedKeysPath + ".tmp && mv -f " +
+		authorizedKeysPath + ".tmp " + authorizedKeysPath
+	if _, err := c.run(command, &content); err != nil {
+		return errors.Wrap(err, "unable to write authorized_keys")
+	}
+
+	return nil
+}
+
+// matchAuthorizedKey 判断 authorized_keys 中的一行是否为指定的公钥。
+func matchAuthorizedKey(line string, key ssh.PublicKey) bool {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return false
+	}
+
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(parsed.Marshal(), key.Marshal())
+}
+
+// authorizedKeyLine 生成公钥在 authorized_keys 文件中的一行内容。
+func authorizedKeyLine(key ssh.PublicKey, comment string) string {
+	line := strings.TrimSuffix(string(ssh.MarshalAuthorizedKey(key)), "\n")
+	if comment != "" {
+		line += " " + comment
+	}
+
+	return line
+}
@@ -0,0 +1,64 @@
+package ssh
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGenerateKey(t *testing.T) {
+	for _, keyType := range []KeyType{KeyTypeEd25519, KeyTypeRSA, KeyTypeECDSA} {
+		bits := 0
+		if keyType == KeyTypeRSA {
+			// 测试中使用较短的 RSA 密钥以缩短耗时
+			bits = 2048
+		}
+
+		pair, err := GenerateKey(keyType, bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pemBytes, err := pair.MarshalPrivateKey("test@cotton", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		signer, err := ssh.ParsePrivateKey(pemBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ssh.FingerprintSHA256(signer.PublicKey()) != pair.Fingerprint() {
+			t.Fatalf("fingerprint mismatch for key type %d", keyType)
+		}
+
+		line := pair.AuthorizedKey("test@cotton")
+		if !strings.HasSuffix(line, " test@cotton") {
+			t.Fatalf("unexpected authorized key %q", line)
+		}
+		if !matchAuthorizedKey(`no-pty `+line, pair.PublicKey) {
+			t.Fatalf("authorized key %q does not match", line)
+		}
+	}
+}
+
+func TestMatchAuthorizedKey(t *testing.T) {
+	a, err := GenerateKey(KeyTypeEd25519, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := GenerateKey(KeyTypeEd25519, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if matchAuthorizedKey(a.AuthorizedKey(""), b.PublicKey) {
+		t.Fatal("different keys must not match")
+	}
+	for _, line := range []string{"", "# comment", "garbage"} {
+		if matchAuthorizedKey(line, a.PublicKey) {
+			t.Fatalf("line %q must not match", line)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package ssh
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// 日志事件中使用的属性名称，保证所有 SSH 事件的字段保持一致，便于日志检索。
+const (
+	LogKeyHost        = "host"        // SSH 远程主机地址，格式为 host:port
+	LogKeyUser        = "user"        // SSH 登录用户名
+	LogKeyAuth        = "auth"        // 使用的认证方式
+	LogKeyFingerprint = "fingerprint" // 远程主机公钥的 SHA256 指纹
+	LogKeyNetwork     = "network"     // 转发通道的网络类型，例如 tcp、unix
+	LogKeyTarget      = "target"      // 转发通道的目标地址
+	LogKeyDuration    = "duration"    // 操作耗时
+)
+
+// redacted 用于替换日志中的敏感信息。
+const redacted = "[REDACTED]"
+
+// String 返回认证类型的可读名称，用于日志输出。
+func (t ConfigType) String() string {
+	switch t {
+	case ConfigTypeByPassword:
+		return "password"
+	case ConfigTypeByPrivateKey:
+		return "private_key"
+	case ConfigTypeByPrivateKeyPath:
+		return "private_key_path"
+	default:
+		return "unknown"
+	}
+}
+
+// LogValue 实现 slog.LogValuer 接口。
+// 密码与私钥内容会被替换为 [REDACTED]，保证配置可以安全地写入日志。
+func (c Config) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("host", c.Host),
+		slog.Int("port", c.Port),
+		slog.String("user", c.User),
+		slog.String("type", c.Type.String()),
+	}
+	if c.Password != "" {
+		attrs = append(attrs, slog.String("password", redacted))
+	}
+	if c.PrivateKey != "" {
+		attrs = append(attrs, slog.String("privateKey", redacted))
+	}
+	if c.PrivateKeyPath != "" {
+		attrs = append(attrs, slog.String("privateKeyPath", c.PrivateKeyPath))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// logger 返回配置中的日志记录器，未配置时返回一个丢弃所有日志的记录器。
+func (c Config) logger() *slog.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+
+	return slog.New(discardHandler{})
+}
+
+// logHostKey 包装主机公钥校验回调，在校验前记录远程主机公钥的指纹。
+//
+// 参数:
+//   - logger: 用于输出日志的记录器。
+//   - callback: 原始的主机公钥校验回调。
+//
+// 返回值:
+//   - ssh.HostKeyCallback: 包装后的主机公钥校验回调。
+func logHostKey(logger *slog.Logger, callback ssh.HostKeyCallback) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		logger.Info("ssh host key received",
+			slog.String("keyType", key.Type()),
+			slog.String(LogKeyFingerprint, ssh.FingerprintSHA256(key)),
+		)
+
+		return callback(hostname, remote, key)
+	}
+}
+
+// logDial 记录一次通过 SSH 连接转发的拨号结果。
+//
+// 参数:
+//   - logger: 用于输出日志的记录器。
+//   - network: 转发通道的网络类型。
+//   - target: 转发通道的目标地址。
+//   - start: 拨号开始的时间。
+//   - err: 拨号返回的错误信息。
+func logDial(logger *slog.Logger, network, target string, start time.Time, err error) {
+	attrs := []any{
+		slog.String(LogKeyNetwork, network),
+		slog.String(LogKeyTarget, target),
+		slog.Duration(LogKeyDuration, time.Since(start)),
+	}
+	if err != nil {
+		logger.Warn("ssh dial failed", append(attrs, slog.Any("error", err))...)
+		return
+	}
+
+	logger.Debug("ssh dial", attrs...)
+}
+
+// discardHandler 是一个丢弃所有日志记录的 slog.Handler。
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
@@ -0,0 +1,374 @@
+package ssh
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+)
+
+// Registry 按 SSH 配置共享 SSH 连接，相同配置只建立一次连接，并通过引用计数管理连接的生命周期。
+// 多个数据库位于同一台跳板机之后时，可以共用同一个 SSH 连接。
+type Registry struct {
+	mu      sync.Mutex
+	clients map[string]*registryEntry
+}
+
+// registryEntry 记录一个共享的 SSH 连接及其引用次数。
+type registryEntry struct {
+	ready  chan struct{} // 首次连接完成后关闭
+	client *Client
+	err    error // 首次连接失败的原因
+	refs   int
+	conf   Config     // 建立连接时使用的配置，用于断开后重新连接
+	heal   sync.Mutex // 保证同一个连接断开后只重新连接一次
+}
+
+// DefaultRegistry 是进程内所有数据库驱动共享的 Registry。
+// 位于同一台跳板机之后的 MySQL、PostgreSQL 等数据库使用相同的 SSH 配置时，无论使用哪个驱动都只建立一个 SSH 连接。
+// 各驱动通过 Group 记录自己获取的引用，关闭一个驱动的连接不会影响其他驱动。
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry 创建一个新的 Registry 实例。
+func NewRegistry() *Registry {
+	return &Registry{clients: make(map[string]*registryEntry)}
+}
+
+// Key 返回用于标识 SSH 连接的唯一键，相同主机、端口、认证类型、用户与凭据的配置返回相同的键。
+// 键中只包含凭据的摘要，不包含密码或私钥本身。
+func (c Config) Key() string {
+	port := c.Port
+	if port == 0 {
+		port = 22
+	}
+
+	var secret string
+	switch c.Type {
+	case ConfigTypeByPassword:
+		secret = c.Password
+	case ConfigTypeByPrivateKey:
+		secret = c.PrivateKey
+	case ConfigTypeByPrivateKeyPath:
+		secret = c.PrivateKeyPath
+	}
+	sum := sha256.Sum256([]byte(secret))
+
+	return fmt.Sprintf("%s-%d-%d-%s-%x", c.Host, port, c.Type, c.User, sum[:6])
+}
+
+// Acquire 获取配置对应的 SSH 连接，连接不存在时使用 Connect 建立，并将引用次数加一。
+// 建立连接时不持有 Registry 的锁，不同配置的连接可以同时建立，相同配置的并发调用等待同一次连接的结果。
+//
+// 参数:
+//   - conf: SSH 连接配置。
+//
+// 返回值:
+//   - *Client: 共享的 SSH 客户端。
+//   - error: 如果建立连接失败，则返回错误信息。
+func (r *Registry) Acquire(conf Config) (*Client, error) {
+	key := conf.Key()
+
+	r.mu.Lock()
+	if entry, ok := r.clients[key]; ok {
+		entry.refs++
+		r.mu.Unlock()
+
+		<-entry.ready
+		if entry.err != nil {
+			return nil, entry.err
+		}
+		return entry.client, nil
+	}
+
+	entry := &registryEntry{ready: make(chan struct{}), refs: 1, conf: conf}
+	r.clients[key] = entry
+	r.mu.Unlock()
+
+	client, err := Connect(conf)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer close(entry.ready)
+
+	if err != nil {
+		// 等待同一次连接的调用也会得到该错误，它们的引用随条目一起移除
+		entry.err = err
+		if r.clients[key] == entry {
+			delete(r.clients, key)
+		}
+		return nil, err
+	}
+
+	if r.clients[key] != entry {
+		// 连接期间 Registry 被关闭
+		client.Close()
+		entry.err = ErrTunnelClosed
+		return nil, ErrTunnelClosed
+	}
+
+	entry.client = client
+	return client, nil
+}
+
+// Client 根据键查找已建立的 SSH 连接。
+//
+// 参数:
+//   - key: 由 Config.Key 生成的键。
+//
+// 返回值:
+//   - *Client: 共享的 SSH 客户端。
+//   - bool: 连接是否存在。
+func (r *Registry) Client(key string) (*Client, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.clients[key]
+	if !ok || entry.client == nil {
+		return nil, false
+	}
+
+	return entry.client, true
+}
+
+// DialContext 通过键对应的 SSH 连接拨号，连接断开时自动重新连接。
+// 拨号失败且 SSH 连接已经不可用时，使用最初的配置重新建立连接并替换共享的连接，然后重试一次拨号。
+// 并发拨号同时发现连接断开时只会重新连接一次，其他拨号等待并使用新的连接。
+//
+// 参数:
+//   - ctx: 用于控制连接超时或取消的上下文。
+//   - key: 由 Config.Key 生成的键。
+//   - network: 网络类型，例如 "tcp"、"unix"，"unix" 会转发到远程主机上的 Unix 域套接字。
+//   - address: 要连接的网络地址。
+//
+// 返回值:
+//   - net.Conn: 建立的网络连接。
+//   - error: 如果连接已被释放、重新连接失败或重试拨号失败，则返回错误信息。
+func (r *Registry) DialContext(ctx context.Context, key, network, address string) (net.Conn, error) {
+	client, ok := r.Client(key)
+	if !ok {
+		return nil, ErrTunnelClosed
+	}
+
+	conn, err := client.DialContext(ctx, network, address)
+	if err == nil || client.Alive(ctx) {
+		return conn, err
+	}
+
+	client, err = r.reconnect(key, client, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.DialContext(ctx, network, address)
+}
+
+// reconnect 使用最初的配置重新建立键对应的 SSH 连接，并关闭已断开的连接。
+// 共享的连接已经不是 broken 时说明其他拨号已经重新连接，直接返回当前的连接。
+func (r *Registry) reconnect(key string, broken *Client, cause error) (*Client, error) {
+	r.mu.Lock()
+	entry, ok := r.clients[key]
+	r.mu.Unlock()
+	if !ok {
+		return nil, ErrTunnelClosed
+	}
+
+	// 旧连接的引用已全部释放，同一配置正在重新建立连接
+	<-entry.ready
+	if entry.err != nil {
+		return nil, entry.err
+	}
+
+	entry.heal.Lock()
+	defer entry.heal.Unlock()
+
+	r.mu.Lock()
+	current := entry.client
+	r.mu.Unlock()
+	if current != broken {
+		return current, nil
+	}
+
+	logger := broken.log()
+	logger.Warn("ssh connection lost, reconnecting", slog.Any("error", cause))
+
+	client, err := Connect(entry.conf)
+	if err != nil {
+		logger.Error("ssh reconnect failed", slog.Any("error", err))
+		return nil, err
+	}
+
+	r.mu.Lock()
+	if r.clients[key] != entry {
+		// 重新连接期间引用已全部释放
+		r.mu.Unlock()
+		client.Close()
+		return nil, ErrTunnelClosed
+	}
+	entry.client = client
+	r.mu.Unlock()
+
+	// 旧连接已经不可用，关闭时的错误不影响结果
+	_ = broken.conn.Close()
+	logger.Info("ssh reconnected")
+
+	return client, nil
+}
+
+// Release 将键对应的 SSH 连接的引用次数减一，引用全部释放后关闭连接。
+//
+// 参数:
+//   - key: 由 Config.Key 生成的键。
+//
+// 返回值:
+//   - bool: 连接是否已被关闭并移除。
+//   - error: 如果关闭连接失败，则返回错误信息。
+func (r *Registry) Release(key string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.clients[key]
+	if !ok {
+		return true, nil
+	}
+
+	entry.refs--
+	if entry.refs > 0 {
+		return false, nil
+	}
+
+	delete(r.clients, key)
+	if entry.client == nil {
+		// 连接尚未建立，Acquire 完成时会关闭它
+		return true, nil
+	}
+	return true, entry.client.Close()
+}
+
+// Close 关闭所有 SSH 连接，通常在程序退出时调用。
+//
+// 返回值:
+//   - []string: 被关闭的连接的键。
+//   - error: 关闭过程中遇到的第一个错误。
+func (r *Registry) Close() ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var (
+		keys     []string
+		firstErr error
+	)
+	for key, entry := range r.clients {
+		if entry.client == nil {
+			// 正在建立的连接在 Acquire 完成时关闭
+			continue
+		}
+
+		keys = append(keys, key)
+		if err := entry.client.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	r.clients = make(map[string]*registryEntry)
+	return keys, firstErr
+}
+
+// Group 记录一个使用方从 Registry 获取的引用，例如一个数据库驱动。
+// 多个使用方共享同一个 Registry 时，Group.Close 只释放本组获取的引用，其他使用方仍在使用的 SSH 连接保持打开。
+type Group struct {
+	registry *Registry
+
+	mu   sync.Mutex
+	refs map[string]int
+}
+
+// Group 创建一个基于该 Registry 的 Group。
+func (r *Registry) Group() *Group {
+	return &Group{registry: r, refs: make(map[string]int)}
+}
+
+// Acquire 与 Registry.Acquire 相同，并记录本组获取的引用。
+//
+// 参数:
+//   - conf: SSH 连接配置。
+//
+// 返回值:
+//   - *Client: 共享的 SSH 客户端。
+//   - error: 如果建立连接失败，则返回错误信息。
+func (g *Group) Acquire(conf Config) (*Client, error) {
+	client, err := g.registry.Acquire(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	g.refs[conf.Key()]++
+	g.mu.Unlock()
+
+	return client, nil
+}
+
+// Release 释放本组对键对应连接的一次引用，本组未持有该连接时不做任何操作。
+//
+// 参数:
+//   - key: 由 Config.Key 生成的键。
+//
+// 返回值:
+//   - bool: 本组是否已经不再持有该连接。
+//   - error: 如果关闭连接失败，则返回错误信息。
+func (g *Group) Release(key string) (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	refs, ok := g.refs[key]
+	if !ok {
+		return true, nil
+	}
+
+	if refs > 1 {
+		g.refs[key] = refs - 1
+	} else {
+		delete(g.refs, key)
+	}
+
+	_, err := g.registry.Release(key)
+	return refs == 1, err
+}
+
+// Close 释放本组获取的所有引用，引用全部释放的连接会被关闭，其他使用方仍在使用的连接保持打开。
+//
+// 返回值:
+//   - []string: 本组持有过的连接的键。
+//   - error: 关闭过程中遇到的第一个错误。
+func (g *Group) Close() ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var (
+		keys     []string
+		firstErr error
+	)
+	for key, refs := range g.refs {
+		keys = append(keys, key)
+		for ; refs > 0; refs-- {
+			if _, err := g.registry.Release(key); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	g.refs = make(map[string]int)
+	return keys, firstErr
+}
+
+// Client 与 Registry.Client 相同。
+func (g *Group) Client(key string) (*Client, bool) {
+	return g.registry.Client(key)
+}
+
+// DialContext 与 Registry.DialContext 相同，通过键对应的 SSH 连接拨号，连接断开时自动重新连接。
+func (g *Group) DialContext(ctx context.Context, key, network, address string) (net.Conn, error) {
+	return g.registry.DialContext(ctx, key, network, address)
+}
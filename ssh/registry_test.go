@@ -0,0 +1,317 @@
+package ssh
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// newTestServer 启动一个只接受密码认证的本地 SSH 服务器，返回其配置。
+// 服务器只接受会话通道，由 serveTestSession 处理，其余通道均被拒绝。
+// handshakes 不为 nil 时记录成功建立的 SSH 连接数。
+func newTestServer(t *testing.T, handshakes *atomic.Int32) Config {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverConfig := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			return nil, nil
+		},
+	}
+	serverConfig.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(conn, serverConfig)
+				if err != nil {
+					return
+				}
+				if handshakes != nil {
+					handshakes.Add(1)
+				}
+
+				go ssh.DiscardRequests(reqs)
+				for ch := range chans {
+					if ch.ChannelType() != "session" {
+						ch.Reject(ssh.Prohibited, "test server")
+						continue
+					}
+
+					channel, requests, err := ch.Accept()
+					if err != nil {
+						continue
+					}
+					go serveTestSession(channel, requests)
+				}
+			}()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+
+	return Config{Host: host, Port: p, User: "ops", Password: "secret"}
+}
+
+// serveTestSession 处理测试服务器上的一个会话。
+// exec 请求的命令为 "cat" 时将标准输入原样写回标准输出，其他命令输出 "ran <命令>"，
+// 命令以 "exit " 开头时使用其后的数字作为退出码，其余请求直接回复成功。
+func serveTestSession(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+
+	for req := range requests {
+		if req.Type != "exec" {
+			req.Reply(true, nil)
+			continue
+		}
+
+		var payload struct{ Command string }
+		if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+			req.Reply(false, nil)
+			return
+		}
+		req.Reply(true, nil)
+
+		var status uint32
+		switch {
+		case payload.Command == "cat":
+			io.Copy(channel, channel)
+		case strings.HasPrefix(payload.Command, "exit "):
+			code, _ := strconv.Atoi(strings.TrimPrefix(payload.Command, "exit "))
+			status = uint32(code)
+		default:
+			io.WriteString(channel, "ran "+payload.Command+"\n")
+		}
+
+		channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+		return
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	conf := newTestServer(t, nil)
+	registry := NewRegistry()
+
+	a, err := registry.Acquire(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := registry.Acquire(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Fatal("same config must share one client")
+	}
+
+	if closed, err := registry.Release(conf.Key()); err != nil || closed {
+		t.Fatalf("first release: closed=%v err=%v", closed, err)
+	}
+	if _, ok := registry.Client(conf.Key()); !ok {
+		t.Fatal("client released too early")
+	}
+	if closed, err := registry.Release(conf.Key()); err != nil || !closed {
+		t.Fatalf("second release: closed=%v err=%v", closed, err)
+	}
+	if _, ok := registry.Client(conf.Key()); ok {
+		t.Fatal("client must be removed after the last release")
+	}
+
+	if _, err := registry.Acquire(conf); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := registry.Close()
+	if err != nil || len(keys) != 1 || keys[0] != conf.Key() {
+		t.Fatalf("close: keys=%v err=%v", keys, err)
+	}
+}
+
+func TestRegistryGroup(t *testing.T) {
+	conf := newTestServer(t, nil)
+	registry := NewRegistry()
+	mysql, postgres := registry.Group(), registry.Group()
+
+	for _, g := range []*Group{mysql, mysql, postgres} {
+		if _, err := g.Acquire(conf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 一个组关闭时只释放自己的引用，另一个组的连接保持打开
+	keys, err := mysql.Close()
+	if err != nil || len(keys) != 1 || keys[0] != conf.Key() {
+		t.Fatalf("close: keys=%v err=%v", keys, err)
+	}
+	if _, ok := postgres.Client(conf.Key()); !ok {
+		t.Fatal("closing one group must not close connections held by another group")
+	}
+
+	// 未持有的连接不会被释放
+	if released, err := mysql.Release(conf.Key()); err != nil || !released {
+		t.Fatalf("release without refs: released=%v err=%v", released, err)
+	}
+	if _, ok := registry.Client(conf.Key()); !ok {
+		t.Fatal("release without refs must not touch the registry")
+	}
+
+	if released, err := postgres.Release(conf.Key()); err != nil || !released {
+		t.Fatalf("release: released=%v err=%v", released, err)
+	}
+	if _, ok := registry.Client(conf.Key()); ok {
+		t.Fatal("client must be removed after the last release")
+	}
+}
+
+func TestRegistryReconnect(t *testing.T) {
+	var handshakes atomic.Int32
+	conf := newTestServer(t, &handshakes)
+	registry := NewRegistry()
+	defer registry.Close()
+
+	original, err := registry.Acquire(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// 连接可用时，拨号失败不会触发重新连接
+	if _, err := registry.DialContext(ctx, conf.Key(), "tcp", "db:5432"); err == nil {
+		t.Fatal("test server must reject channels")
+	}
+	if current, _ := registry.Client(conf.Key()); current != original || handshakes.Load() != 1 {
+		t.Fatalf("alive client was replaced, handshakes = %d", handshakes.Load())
+	}
+
+	// 模拟 SSH 连接断开，并发拨号只重新连接一次
+	original.Client().Close()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			registry.DialContext(ctx, conf.Key(), "tcp", "db:5432")
+		}()
+	}
+	wg.Wait()
+
+	current, ok := registry.Client(conf.Key())
+	if !ok || current == original || !current.Alive(ctx) {
+		t.Fatal("broken client was not replaced by a live one")
+	}
+	if got := handshakes.Load(); got != 2 {
+		t.Fatalf("handshakes = %d, want 2", got)
+	}
+
+	if _, err := registry.DialContext(ctx, "missing", "tcp", "db:5432"); !errors.Is(err, ErrTunnelClosed) {
+		t.Fatalf("dial unknown key error = %v, want ErrTunnelClosed", err)
+	}
+}
+
+func TestRegistryAcquireConcurrent(t *testing.T) {
+	var handshakes atomic.Int32
+	conf := newTestServer(t, &handshakes)
+	registry := NewRegistry()
+	defer registry.Close()
+
+	// 一个接受连接后不进行握手的主机，连接它的 Acquire 会一直阻塞到连接关闭
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	release := make(chan struct{})
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		<-release
+		conn.Close()
+	}()
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+	stalled := Config{Host: host, Port: p, User: "ops", Password: "secret"}
+
+	stalledErr := make(chan error, 1)
+	go func() {
+		_, err := registry.Acquire(stalled)
+		stalledErr <- err
+	}()
+
+	// 其他配置的连接不会被正在建立的连接阻塞，相同配置的并发调用共用一次连接
+	var wg sync.WaitGroup
+	clients := make([]*Client, 8)
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			clients[i], _ = registry.Acquire(conf)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, client := range clients {
+		if client == nil || client != clients[0] {
+			t.Fatal("concurrent acquires must share one client")
+		}
+	}
+	if got := handshakes.Load(); got != 1 {
+		t.Fatalf("handshakes = %d, want 1", got)
+	}
+
+	close(release)
+	if err := <-stalledErr; err == nil {
+		t.Fatal("expected stalled connect to fail")
+	}
+	if _, ok := registry.Client(stalled.Key()); ok {
+		t.Fatal("failed connect must not be registered")
+	}
+}
+
+func TestConfigKey(t *testing.T) {
+	a := Config{Host: "bastion", User: "ops", Password: "s3cret!"}
+	b := a
+	b.Password = "s3cret?"
+	if a.Key() == b.Key() {
+		t.Fatal("configs with different passwords must not share a key")
+	}
+
+	c := a
+	c.Port = 22
+	if a.Key() != c.Key() {
+		t.Fatal("default port must produce the same key")
+	}
+	if strings.Contains(a.Key(), a.Password) {
+		t.Fatalf("key %q contains the password", a.Key())
+	}
+}
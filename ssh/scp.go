@@ -0,0 +1,525 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SCPProgressFunc 定义了 SCP 传输进度的回调函数类型。
+// 参数 path 为当前正在传输的本地文件路径，transferred 为已传输的字节数，total 为文件总字节数。
+type SCPProgressFunc func(path string, transferred, total int64)
+
+// SCPOptions 定义了 SCP 传输的选项。
+type SCPOptions struct {
+	// Recursive 表示是否递归传输目录，等同于 scp -r。
+	Recursive bool
+	// Preserve 表示是否保留文件的修改时间、访问时间与权限位，等同于 scp -p。
+	Preserve bool
+	// Progress 传输进度回调，为 nil 时不回调。
+	Progress SCPProgressFunc
+}
+
+// SCPUpload 使用 SCP 协议将本地文件或目录上传到远程主机。
+// 适用于禁用了 sftp 子系统但允许执行 scp 的主机。
+//
+// 参数:
+//   - localPath: 本地文件或目录的路径，上传目录时需要设置 opts.Recursive。
+//   - remotePath: 远程主机上的目标路径，若为已存在的目录则上传到该目录下。
+//   - opts: 传输选项。
+//
+// 返回值:
+//   - error: 如果传输失败，则返回错误信息。
+func (c Client) SCPUpload(localPath, remotePath string, opts SCPOptions) error {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return errors.Wrap(err, "unable to stat local path")
+	}
+
+	// 未开启递归时不允许上传目录
+	if info.IsDir() && !opts.Recursive {
+		return errors.Errorf("%s is a directory, set Recursive to upload it", localPath)
+	}
+
+	start := time.Now()
+	err = c.scp(scpCommand("-t", remotePath, opts), func(w io.Writer, r *bufio.Reader) error {
+		// 远程 scp 准备就绪后会先发送一个确认字节
+		if err := readSCPAck(r); err != nil {
+			return err
+		}
+
+		source := &scpSource{w: w, r: r, opts: opts}
+		return source.send(localPath, info)
+	})
+	logSCP(c.log(), "upload", remotePath, start, err)
+
+	return err
+}
+
+// SCPDownload 使用 SCP 协议将远程主机上的文件或目录下载到本地。
+//
+// 参数:
+//   - remotePath: 远程主机上文件或目录的路径，下载目录时需要设置 opts.Recursive。
+//   - localPath: 本地目标路径，若为已存在的目录则下载到该目录下。
+//   - opts: 传输选项。
+//
+// 返回值:
+//   - error: 如果传输失败，则返回错误信息。
+func (c Client) SCPDownload(remotePath, localPath string, opts SCPOptions) error {
+	start := time.Now()
+	err := c.scp(scpCommand("-f", remotePath, opts), func(w io.Writer, r *bufio.Reader) error {
+		sink := &scpSink{w: w, r: r, opts: opts}
+		return sink.receive(localPath)
+	})
+	logSCP(c.log(), "download", remotePath, start, err)
+
+	return err
+}
+
+// scp 在新的 SSH 会话中执行远程 scp 命令，并通过 fn 在会话的标准输入输出上进行协议交互。
+//
+// 参数:
+//   - command: 在远程主机上执行的 scp 命令。
+//   - fn: 协议交互函数，w 写入远程标准输入，r 读取远程标准输出。
+//
+// 返回值:
+//   - error: 如果会话或协议交互失败，则返回错误信息。
+func (c Client) scp(command string, fn func(w io.Writer, r *bufio.Reader) error) error {
+	session, err := c.conn.NewSession()
+	if err != nil {
+		return errors.Wrap(err, "failed to create ssh session")
+	}
+	defer session.Close()
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return errors.Wrap(err, "failed to open session stdin")
+	}
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return errors.Wrap(err, "failed to open session stdout")
+	}
+
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+	if err := session.Start(command); err != nil {
+		return errors.Wrap(err, "failed to start remote scp")
+	}
+
+	if err := fn(stdin, bufio.NewReader(stdout)); err != nil {
+		_ = stdin.Close()
+		return err
+	}
+
+	// 关闭标准输入通知远程 scp 传输结束
+	_ = stdin.Close()
+	if err := session.Wait(); err != nil {
+		return errors.Wrapf(err, "remote scp failed: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// scpSource 实现 SCP 协议的发送端。
+type scpSource struct {
+	w    io.Writer     // 写入接收端的数据流
+	r    *bufio.Reader // 读取接收端确认信息的数据流
+	opts SCPOptions    // 传输选项
+}
+
+// send 发送一个文件或目录。
+func (s *scpSource) send(path string, info os.FileInfo) error {
+	if info.IsDir() {
+		return s.sendDir(path, info)
+	}
+
+	return s.sendFile(path, info)
+}
+
+// sendTimes 在开启 Preserve 时发送文件的时间信息。
+func (s *scpSource) sendTimes(info os.FileInfo) error {
+	if !s.opts.Preserve {
+		return nil
+	}
+
+	mtime := info.ModTime().Unix()
+	if _, err := fmt.Fprintf(s.w, "T%d 0 %d 0\n", mtime, mtime); err != nil {
+		return errors.Wrap(err, "failed to send scp times")
+	}
+
+	return readSCPAck(s.r)
+}
+
+// sendFile 发送单个普通文件。
+func (s *scpSource) sendFile(path string, info os.FileInfo) error {
+	if !info.Mode().IsRegular() {
+		return errors.Errorf("%s is not a regular file", path)
+	}
+
+	if err := s.sendTimes(info); err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "unable to open local file")
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(s.w, "C%04o %d %s\n", info.Mode().Perm(), info.Size(), info.Name()); err != nil {
+		return errors.Wrap(err, "failed to send scp file header")
+	}
+	if err := readSCPAck(s.r); err != nil {
+		return err
+	}
+
+	w := &scpProgressWriter{w: s.w, path: path, total: info.Size(), progress: s.opts.Progress}
+	if _, err := io.CopyN(w, f, info.Size()); err != nil {
+		return errors.Wrap(err, "failed to send scp file content")
+	}
+
+	// 文件内容之后以一个零字节结束
+	if _, err := s.w.Write([]byte{0}); err != nil {
+		return errors.Wrap(err, "failed to send scp file content")
+	}
+
+	return readSCPAck(s.r)
+}
+
+// sendDir 递归发送目录及其内容。
+func (s *scpSource) sendDir(path string, info os.FileInfo) error {
+	if err := s.sendTimes(info); err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(s.w, "D%04o 0 %s\n", info.Mode().Perm(), info.Name()); err != nil {
+		return errors.Wrap(err, "failed to send scp directory header")
+	}
+	if err := readSCPAck(s.r); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return errors.Wrap(err, "unable to read local directory")
+	}
+
+	for _, entry := range entries {
+		name := filepath.Join(path, entry.Name())
+		// 与 scp 一致，跟随符号链接
+		fi, err := os.Stat(name)
+		if err != nil {
+			return errors.Wrap(err, "unable to stat local path")
+		}
+
+		// 跳过设备文件、套接字等无法传输的文件
+		if !fi.IsDir() && !fi.Mode().IsRegular() {
+			continue
+		}
+
+		if err := s.send(name, fi); err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.WriteString(s.w, "E\n"); err != nil {
+		return errors.Wrap(err, "failed to send scp directory end")
+	}
+
+	return readSCPAck(s.r)
+}
+
+// scpTimes 记录 T 指令携带的时间信息。
+type scpTimes struct {
+	mtime time.Time
+	atime time.Time
+}
+
+// scpDir 记录接收端当前所在的目录及其待设置的时间信息。
+type scpDir struct {
+	path  string
+	times *scpTimes
+}
+
+// scpSink 实现 SCP 协议的接收端。
+type scpSink struct {
+	w    io.Writer     // 写入发送端确认信息的数据流
+	r    *bufio.Reader // 读取发送端的数据流
+	opts SCPOptions    // 传输选项
+}
+
+// receive 接收发送端的全部文件与目录，并写入 target。
+func (s *scpSink) receive(target string) error {
+	var (
+		dirs  []scpDir
+		times *scpTimes
+	)
+
+	// target 为已存在的目录时，接收的内容放在该目录下
+	info, err := os.Stat(target)
+	targetIsDir := err == nil && info.IsDir()
+
+	// 发送一个确认字节通知发送端开始传输
+	if err := s.ack(); err != nil {
+		return err
+	}
+
+	for {
+		line, err := s.r.ReadString('\n')
+		if err == io.EOF && line == "" {
+			// 发送端已发送完毕
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to read scp command")
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return errors.New("scp: empty command")
+		}
+
+		// 计算新条目应写入的本地路径
+		path := func(name string) string {
+			if n := len(dirs); n > 0 {
+				return filepath.Join(dirs[n-1].path, name)
+			}
+			if targetIsDir {
+				return filepath.Join(target, name)
+			}
+			return target
+		}
+
+		switch line[0] {
+		case 1, 2:
+			return errors.Errorf("scp: %s", line[1:])
+		case 'T':
+			var mtime, mtimeUsec, atime, atimeUsec int64
+			if _, err := fmt.Sscanf(line, "T%d %d %d %d", &mtime, &mtimeUsec, &atime, &atimeUsec); err != nil {
+				return errors.Wrapf(err, "scp: invalid times %q", line)
+			}
+
+			times = &scpTimes{mtime: time.Unix(mtime, mtimeUsec*1000), atime: time.Unix(atime, atimeUsec*1000)}
+		case 'C':
+			mode, size, name, err := parseSCPHeader(line)
+			if err != nil {
+				return err
+			}
+
+			if err := s.receiveFile(path(name), mode, size, times); err != nil {
+				return err
+			}
+
+			times = nil
+			// receiveFile 已发送确认信息
+			continue
+		case 'D':
+			if !s.opts.Recursive {
+				return errors.New("scp: received directory but Recursive is not set")
+			}
+
+			mode, _, name, err := parseSCPHeader(line)
+			if err != nil {
+				return err
+			}
+
+			dir := path(name)
+			if err := os.MkdirAll(dir, mode); err != nil {
+				return errors.Wrap(err, "unable to create local directory")
+			}
+			if s.opts.Preserve {
+				if err := os.Chmod(dir, mode); err != nil {
+					return errors.Wrap(err, "unable to set local directory mode")
+				}
+			}
+
+			dirs = append(dirs, scpDir{path: dir, times: times})
+			times = nil
+		case 'E':
+			if len(dirs) == 0 {
+				return errors.New("scp: unexpected directory end")
+			}
+
+			// 目录内容写入完毕后再设置目录的时间，避免被其内容的写入覆盖
+			dir := dirs[len(dirs)-1]
+			dirs = dirs[:len(dirs)-1]
+			if err := s.chtimes(dir.path, dir.times); err != nil {
+				return err
+			}
+		default:
+			return errors.Errorf("scp: unexpected command %q", line)
+		}
+
+		if err := s.ack(); err != nil {
+			return err
+		}
+	}
+}
+
+// receiveFile 接收单个文件的内容并写入 path。
+func (s *scpSink) receiveFile(path string, mode os.FileMode, size int64, times *scpTimes) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return errors.Wrap(err, "unable to create local file")
+	}
+	defer f.Close()
+
+	if err := s.ack(); err != nil {
+		return err
+	}
+
+	w := &scpProgressWriter{w: f, path: path, total: size, progress: s.opts.Progress}
+	if _, err := io.CopyN(w, s.r, size); err != nil {
+		return errors.Wrap(err, "failed to receive scp file content")
+	}
+
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "unable to write local file")
+	}
+
+	// 文件内容之后是发送端的状态字节
+	if err := readSCPAck(s.r); err != nil {
+		return err
+	}
+
+	if s.opts.Preserve {
+		if err := os.Chmod(path, mode); err != nil {
+			return errors.Wrap(err, "unable to set local file mode")
+		}
+	}
+
+	if err := s.chtimes(path, times); err != nil {
+		return err
+	}
+
+	return s.ack()
+}
+
+// chtimes 在开启 Preserve 时设置本地文件的访问时间与修改时间。
+func (s *scpSink) chtimes(path string, times *scpTimes) error {
+	if !s.opts.Preserve || times == nil {
+		return nil
+	}
+
+	if err := os.Chtimes(path, times.atime, times.mtime); err != nil {
+		return errors.Wrap(err, "unable to set local file times")
+	}
+
+	return nil
+}
+
+// ack 向发送端写入一个确认字节。
+func (s *scpSink) ack() error {
+	if _, err := s.w.Write([]byte{0}); err != nil {
+		return errors.Wrap(err, "failed to send scp ack")
+	}
+
+	return nil
+}
+
+// scpProgressWriter 在写入数据的同时回调传输进度。
+type scpProgressWriter struct {
+	w           io.Writer
+	path        string
+	total       int64
+	transferred int64
+	progress    SCPProgressFunc
+}
+
+// Write 实现 io.Writer 接口。
+func (w *scpProgressWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.transferred += int64(n)
+	if w.progress != nil {
+		w.progress(w.path, w.transferred, w.total)
+	}
+
+	return n, err
+}
+
+// readSCPAck 读取对端的确认信息，非零状态会返回对端提供的错误信息。
+func readSCPAck(r *bufio.Reader) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return errors.Wrap(err, "failed to read scp ack")
+	}
+
+	switch b {
+	case 0:
+		return nil
+	case 1, 2:
+		msg, _ := r.ReadString('\n')
+		return errors.Errorf("scp: %s", strings.TrimSpace(msg))
+	default:
+		return errors.Errorf("scp: unexpected ack %q", b)
+	}
+}
+
+// parseSCPHeader 解析 C 或 D 指令，返回权限位、大小与名称。
+// 名称中包含路径分隔符或为 "." ".." 时返回错误，防止写出目标目录之外。
+func parseSCPHeader(line string) (os.FileMode, int64, string, error) {
+	parts := strings.SplitN(line[1:], " ", 3)
+	if len(parts) != 3 {
+		return 0, 0, "", errors.Errorf("scp: invalid header %q", line)
+	}
+
+	var (
+		mode uint32
+		size int64
+	)
+	if _, err := fmt.Sscanf(parts[0], "%o", &mode); err != nil {
+		return 0, 0, "", errors.Wrapf(err, "scp: invalid mode %q", line)
+	}
+	if _, err := fmt.Sscanf(parts[1], "%d", &size); err != nil {
+		return 0, 0, "", errors.Wrapf(err, "scp: invalid size %q", line)
+	}
+
+	name := parts[2]
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\") {
+		return 0, 0, "", errors.Errorf("scp: invalid name %q", name)
+	}
+
+	return os.FileMode(mode).Perm(), size, name, nil
+}
+
+// scpCommand 生成在远程主机上执行的 scp 命令。
+func scpCommand(mode, path string, opts SCPOptions) string {
+	args := []string{"scp", mode}
+	if opts.Recursive {
+		args = append(args, "-r")
+	}
+	if opts.Preserve {
+		args = append(args, "-p")
+	}
+
+	return strings.Join(append(args, shellQuote(path)), " ")
+}
+
+// shellQuote 使用单引号转义字符串，使其可以安全地作为远程 shell 命令的参数。
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// logSCP 记录一次 SCP 传输的结果。
+func logSCP(logger *slog.Logger, direction, target string, start time.Time, err error) {
+	attrs := []any{
+		slog.String("direction", direction),
+		slog.String(LogKeyTarget, target),
+		slog.Duration(LogKeyDuration, time.Since(start)),
+	}
+	if err != nil {
+		logger.Warn("scp transfer failed", append(attrs, slog.Any("error", err))...)
+		return
+	}
+
+	logger.Info("scp transfer", attrs...)
+}
@@ -0,0 +1,121 @@
+package ssh
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSCPRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+
+	mtime := time.Unix(1700000000, 0)
+	if err := os.MkdirAll(filepath.Join(src, "data", "sub"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"data/a.txt":     "hello",
+		"data/sub/b.txt": "world",
+	}
+	for name, content := range files {
+		path := filepath.Join(src, name)
+		if err := os.WriteFile(path, []byte(content), 0o640); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 发送端写入接收端，接收端的确认信息写回发送端
+	toSink, fromSource := io.Pipe()
+	toSource, fromSink := io.Pipe()
+
+	// 发送端与接收端在不同的 goroutine 中回调进度，各自使用独立的选项
+	var sent, received int64
+	sourceOpts := SCPOptions{
+		Recursive: true,
+		Preserve:  true,
+		Progress: func(path string, transferred, total int64) {
+			sent = transferred
+		},
+	}
+	sinkOpts := sourceOpts
+	sinkOpts.Progress = func(path string, transferred, total int64) {
+		received = transferred
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		r := bufio.NewReader(toSource)
+		if err := readSCPAck(r); err != nil {
+			errc <- err
+			return
+		}
+
+		info, err := os.Stat(filepath.Join(src, "data"))
+		if err != nil {
+			errc <- err
+			return
+		}
+
+		source := &scpSource{w: fromSource, r: r, opts: sourceOpts}
+		err = source.send(filepath.Join(src, "data"), info)
+		fromSource.Close()
+		errc <- err
+	}()
+
+	sink := &scpSink{w: fromSink, r: bufio.NewReader(toSink), opts: sinkOpts}
+	if err := sink.receive(dst); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dst, name)
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Fatalf("%s: got %q, want %q", name, got, content)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0o640 {
+			t.Fatalf("%s: got mode %o, want %o", name, info.Mode().Perm(), 0o640)
+		}
+		if !info.ModTime().Equal(mtime) {
+			t.Fatalf("%s: got mtime %v, want %v", name, info.ModTime(), mtime)
+		}
+	}
+
+	if sent != 5 || received != 5 {
+		t.Fatalf("got progress sent=%d received=%d, want 5", sent, received)
+	}
+}
+
+func TestParseSCPHeader(t *testing.T) {
+	mode, size, name, err := parseSCPHeader("C0644 12 file name.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode != 0o644 || size != 12 || name != "file name.txt" {
+		t.Fatalf("unexpected header %o %d %q", mode, size, name)
+	}
+
+	for _, line := range []string{"C0644 1 ..", "C0644 1 ../x", "C0644 1", "Cxyz 1 a"} {
+		if _, _, _, err := parseSCPHeader(line); err == nil {
+			t.Fatalf("expected error for %q", line)
+		}
+	}
+}
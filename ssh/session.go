@@ -0,0 +1,302 @@
+package ssh
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// SessionOptions 定义了创建 SSH 会话时的选项。
+type SessionOptions struct {
+	// Recorder 会话录制的输出，内容为 asciicast v2 格式，为 nil 时不录制。
+	// 可以是打开的 .cast 文件，也可以是任意 io.Writer，例如写入审计系统的写入器。
+	Recorder io.Writer
+	// RecordInput 表示是否同时录制会话的输入，注意输入中可能包含交互时键入的密码。
+	RecordInput bool
+	// Width 录制时的终端宽度，为 0 时使用 RequestPty 的宽度或默认值 80。
+	Width int
+	// Height 录制时的终端高度，为 0 时使用 RequestPty 的高度或默认值 24。
+	Height int
+	// Title 录制的标题。
+	Title string
+}
+
+// Session 是一个 SSH 会话，在 ssh.Session 的基础上支持将会话的输入输出录制为 asciicast v2 格式。
+// 录制从 Start、Shell 或 Run 开始，在 Wait 返回时写入退出码并结束。
+type Session struct {
+	*ssh.Session
+	client Client
+	opts   SessionOptions
+	env    map[string]string
+	cast   atomic.Pointer[AsciicastWriter]
+	cmd    string
+	start  time.Time
+}
+
+// NewSession 在当前 SSH 连接上创建一个新的会话。
+//
+// 参数:
+//   - opts: 会话选项，用于配置会话录制。
+//
+// 返回值:
+//   - *Session: 创建的会话，使用完毕后需要调用 Close 关闭。
+//   - error: 如果创建失败，则返回错误信息。
+func (c Client) NewSession(opts SessionOptions) (*Session, error) {
+	session, err := c.conn.NewSession()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create ssh session")
+	}
+
+	return &Session{Session: session, client: c, opts: opts, env: make(map[string]string)}, nil
+}
+
+// RequestPty 请求一个伪终端，并记录终端类型与尺寸用于录制。
+func (s *Session) RequestPty(term string, h, w int, termmodes ssh.TerminalModes) error {
+	if s.opts.Width == 0 {
+		s.opts.Width = w
+	}
+	if s.opts.Height == 0 {
+		s.opts.Height = h
+	}
+	s.env["TERM"] = term
+
+	return s.Session.RequestPty(term, h, w, termmodes)
+}
+
+// WindowChange 通知远程主机终端尺寸发生变化，并在录制中记录该事件。
+func (s *Session) WindowChange(h, w int) error {
+	if cast := s.cast.Load(); cast != nil {
+		if err := cast.Resize(w, h); err != nil {
+			return err
+		}
+	}
+
+	return s.Session.WindowChange(h, w)
+}
+
+// StdinPipe 返回一个连接到远程命令标准输入的管道，开启 RecordInput 时写入的内容会被录制。
+func (s *Session) StdinPipe() (io.WriteCloser, error) {
+	pipe, err := s.Session.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if !s.recording() || !s.opts.RecordInput {
+		return pipe, nil
+	}
+
+	return recordWriteCloser{WriteCloser: pipe, w: io.MultiWriter(pipe, s.writer(AsciicastInput))}, nil
+}
+
+// StdoutPipe 返回一个连接到远程命令标准输出的管道，读取的内容会被录制。
+func (s *Session) StdoutPipe() (io.Reader, error) {
+	pipe, err := s.Session.StdoutPipe()
+	if err != nil || !s.recording() {
+		return pipe, err
+	}
+
+	return io.TeeReader(pipe, s.writer(AsciicastOutput)), nil
+}
+
+// StderrPipe 返回一个连接到远程命令标准错误的管道，读取的内容会被录制。
+func (s *Session) StderrPipe() (io.Reader, error) {
+	pipe, err := s.Session.StderrPipe()
+	if err != nil || !s.recording() {
+		return pipe, err
+	}
+
+	return io.TeeReader(pipe, s.writer(AsciicastOutput)), nil
+}
+
+// Start 在远程主机上启动命令并开始录制，不等待命令结束。
+func (s *Session) Start(cmd string) error {
+	if err := s.begin(cmd); err != nil {
+		return err
+	}
+
+	return s.Session.Start(cmd)
+}
+
+// Shell 在远程主机上启动交互式 shell 并开始录制。
+func (s *Session) Shell() error {
+	if err := s.begin(""); err != nil {
+		return err
+	}
+
+	return s.Session.Shell()
+}
+
+// Wait 等待远程命令结束，并在录制中写入退出码。
+func (s *Session) Wait() error {
+	err := s.Session.Wait()
+	exitCode := exitStatus(err)
+
+	s.client.log().Info("ssh session finished",
+		slog.String("command", s.cmd),
+		slog.Int("exitCode", exitCode),
+		slog.Duration(LogKeyDuration, time.Since(s.start)),
+	)
+
+	if cast := s.cast.Load(); cast != nil {
+		if cerr := cast.Close(exitCode); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+// Run 在远程主机上执行命令、录制并等待其结束。
+func (s *Session) Run(cmd string) error {
+	if err := s.Start(cmd); err != nil {
+		return err
+	}
+
+	return s.Wait()
+}
+
+// Output 执行命令并返回其标准输出。
+func (s *Session) Output(cmd string) ([]byte, error) {
+	if s.Stdout != nil {
+		return nil, errors.New("ssh: Stdout already set")
+	}
+
+	var b bytes.Buffer
+	s.Stdout = &b
+	err := s.Run(cmd)
+
+	return b.Bytes(), err
+}
+
+// CombinedOutput 执行命令并返回其标准输出与标准错误的合并内容。
+func (s *Session) CombinedOutput(cmd string) ([]byte, error) {
+	if s.Stdout != nil {
+		return nil, errors.New("ssh: Stdout already set")
+	}
+	if s.Stderr != nil {
+		return nil, errors.New("ssh: Stderr already set")
+	}
+
+	var b singleWriter
+	s.Stdout = &b
+	s.Stderr = &b
+	err := s.Run(cmd)
+
+	return b.b.Bytes(), err
+}
+
+// begin 记录命令并在配置了 Recorder 时写入录制文件头，同时将标准输入输出接入录制。
+func (s *Session) begin(cmd string) error {
+	s.cmd = cmd
+	s.start = time.Now()
+	if !s.recording() {
+		return nil
+	}
+
+	header := AsciicastHeader{
+		Width:   s.opts.Width,
+		Height:  s.opts.Height,
+		Command: cmd,
+		Title:   s.opts.Title,
+		User:    s.client.conn.User(),
+		Host:    s.client.conn.RemoteAddr().String(),
+	}
+	if len(s.env) > 0 {
+		header.Env = s.env
+	}
+
+	cast, err := NewAsciicastWriter(s.opts.Recorder, header)
+	if err != nil {
+		return err
+	}
+	s.cast.Store(cast)
+
+	// 通过管道读写的内容已在 StdinPipe 等方法中接入录制，这里只处理直接设置的字段
+	if s.Stdout != nil {
+		s.Stdout = io.MultiWriter(s.Stdout, s.writer(AsciicastOutput))
+	} else {
+		s.Stdout = s.writer(AsciicastOutput)
+	}
+	if s.Stderr != nil {
+		s.Stderr = io.MultiWriter(s.Stderr, s.writer(AsciicastOutput))
+	} else {
+		s.Stderr = s.writer(AsciicastOutput)
+	}
+	if s.Stdin != nil && s.opts.RecordInput {
+		s.Stdin = io.TeeReader(s.Stdin, s.writer(AsciicastInput))
+	}
+
+	return nil
+}
+
+// recording 判断是否开启了会话录制。
+func (s *Session) recording() bool {
+	return s.opts.Recorder != nil
+}
+
+// writer 返回一个将内容写入录制的 io.Writer。
+// 录制在 Start 之前尚未开始，因此每次写入时才查找当前的录制写入器。
+func (s *Session) writer(kind string) io.Writer {
+	return sessionEventWriter{s: s, kind: kind}
+}
+
+// sessionEventWriter 将写入内容记录为会话录制中的事件。
+type sessionEventWriter struct {
+	s    *Session
+	kind string
+}
+
+// Write 实现 io.Writer 接口。
+func (w sessionEventWriter) Write(p []byte) (int, error) {
+	if cast := w.s.cast.Load(); cast != nil {
+		if err := cast.WriteEvent(w.kind, p); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// recordWriteCloser 在写入管道的同时录制输入，关闭时只关闭管道。
+type recordWriteCloser struct {
+	io.WriteCloser
+	w io.Writer
+}
+
+// Write 实现 io.Writer 接口。
+func (r recordWriteCloser) Write(p []byte) (int, error) {
+	return r.w.Write(p)
+}
+
+// singleWriter 是一个可以被标准输出与标准错误并发写入的缓冲区。
+type singleWriter struct {
+	b  bytes.Buffer
+	mu sync.Mutex
+}
+
+// Write 实现 io.Writer 接口。
+func (w *singleWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.b.Write(p)
+}
+
+// exitStatus 从 Wait 返回的错误中提取退出码，无法确定时返回 -1。
+func exitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus()
+	}
+
+	return -1
+}
@@ -0,0 +1,153 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// readCast 解析 asciicast v2 格式的录制，返回文件头与 "类型:内容" 形式的事件列表。
+func readCast(t *testing.T, r io.Reader) (AsciicastHeader, []string) {
+	t.Helper()
+
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		t.Fatal("missing header")
+	}
+
+	var header AsciicastHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []string
+	for scanner.Scan() {
+		var event []any
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event[1].(string)+":"+event[2].(string))
+	}
+
+	return header, events
+}
+
+// joinEvents 按顺序拼接指定类型事件的内容。
+func joinEvents(events []string, kind string) string {
+	var b strings.Builder
+	for _, event := range events {
+		if data, ok := strings.CutPrefix(event, kind+":"); ok {
+			b.WriteString(data)
+		}
+	}
+
+	return b.String()
+}
+
+func TestSessionRecord(t *testing.T) {
+	client, err := Connect(newTestServer(t, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// Run 写入文件头、输出事件与退出码
+	var cast bytes.Buffer
+	session, err := client.NewSession(SessionOptions{Recorder: &cast, Title: "deploy", Width: 120})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout bytes.Buffer
+	session.Stdout = &stdout
+	if err := session.Run("uptime"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	session.Close()
+
+	header, events := readCast(t, &cast)
+	if header.Version != 2 || header.Command != "uptime" || header.Title != "deploy" || header.Width != 120 ||
+		header.User != "ops" || !strings.HasPrefix(header.Host, "127.0.0.1:") {
+		t.Fatalf("unexpected header %+v", header)
+	}
+	if got := joinEvents(events, "o"); got != "ran uptime\n" || stdout.String() != got {
+		t.Fatalf("output events %q, stdout %q, want %q", got, stdout.String(), "ran uptime\n")
+	}
+	if events[len(events)-1] != "m:exit:0" {
+		t.Fatalf("last event %q, want exit marker", events[len(events)-1])
+	}
+
+	// 非零退出码同样写入录制
+	cast.Reset()
+	session, err = client.NewSession(SessionOptions{Recorder: &cast})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exitErr *ssh.ExitError
+	if err := session.Run("exit 3"); !errors.As(err, &exitErr) || exitErr.ExitStatus() != 3 {
+		t.Fatalf("got %v, want exit status 3", err)
+	}
+	session.Close()
+	if _, events := readCast(t, &cast); events[len(events)-1] != "m:exit:3" {
+		t.Fatalf("events %q, want exit:3 marker", events)
+	}
+
+	// 通过 StdinPipe 写入的输入只在开启 RecordInput 时录制
+	for _, recordInput := range []bool{true, false} {
+		cast.Reset()
+		session, err := client.NewSession(SessionOptions{Recorder: &cast, RecordInput: recordInput})
+		if err != nil {
+			t.Fatal(err)
+		}
+		stdin, err := session.StdinPipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stdout, err := session.StdoutPipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := session.Start("cat"); err != nil {
+			t.Fatal(err)
+		}
+
+		io.WriteString(stdin, "s3cret\n")
+		stdin.Close()
+		echoed, _ := io.ReadAll(stdout)
+		if err := session.Wait(); err != nil {
+			t.Fatalf("wait: %v", err)
+		}
+		session.Close()
+
+		_, events := readCast(t, &cast)
+		if string(echoed) != "s3cret\n" || joinEvents(events, "o") != "s3cret\n" {
+			t.Fatalf("echoed %q, output events %q", echoed, events)
+		}
+		want := ""
+		if recordInput {
+			want = "s3cret\n"
+		}
+		if got := joinEvents(events, "i"); got != want {
+			t.Fatalf("RecordInput=%v: input events %q, want %q", recordInput, got, want)
+		}
+	}
+
+	// 未设置 Recorder 时不录制，也不替换调用方的输出
+	session, err = client.NewSession(SessionOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+	out, err := session.Output("uptime")
+	if err != nil || string(out) != "ran uptime\n" {
+		t.Fatalf("output %q, %v", out, err)
+	}
+	if session.cast.Load() != nil {
+		t.Fatal("session without Recorder must not record")
+	}
+}
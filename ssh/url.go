@@ -0,0 +1,107 @@
+package ssh
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// 连接 URL 中表示 SSH 配置的查询参数，例如 mysql+ssh://user:pw@db:3306/app?ssh_host=bastion&ssh_user=ops&ssh_key=/keys/id
+const (
+	QueryHost       = "ssh_host"        // SSH 主机地址
+	QueryPort       = "ssh_port"        // SSH 端口，默认为 22
+	QueryUser       = "ssh_user"        // SSH 登录用户名
+	QueryPassword   = "ssh_password"    // SSH 登录密码
+	QueryKey        = "ssh_key"         // SSH 私钥文件路径
+	QueryPrivateKey = "ssh_private_key" // SSH 私钥内容
+)
+
+// RedactedSecret 是 URL 中密码等敏感信息被隐藏后显示的内容，与 url.URL.Redacted 一致。
+const RedactedSecret = "xxxxx"
+
+// ConfigFromQuery 从 URL 查询参数中解析 SSH 配置，并从 query 中删除这些参数，
+// 剩余的参数可以继续交给数据库驱动处理。
+// 认证方式按 ssh_password、ssh_private_key、ssh_key 的顺序选择第一个非空的参数。
+//
+// 参数:
+//   - query: URL 的查询参数，会被修改。
+//
+// 返回值:
+//   - *Config: 解析得到的 SSH 配置，没有 ssh_host 参数时返回 nil。
+//   - error: 如果参数格式错误，则返回错误信息。
+func ConfigFromQuery(query url.Values) (*Config, error) {
+	conf := &Config{
+		Host:           query.Get(QueryHost),
+		User:           query.Get(QueryUser),
+		Password:       query.Get(QueryPassword),
+		PrivateKey:     query.Get(QueryPrivateKey),
+		PrivateKeyPath: query.Get(QueryKey),
+	}
+
+	port := query.Get(QueryPort)
+	for _, key := range []string{QueryHost, QueryPort, QueryUser, QueryPassword, QueryKey, QueryPrivateKey} {
+		query.Del(key)
+	}
+
+	if conf.Host == "" {
+		if conf.User != "" || conf.Password != "" || conf.PrivateKey != "" || conf.PrivateKeyPath != "" || port != "" {
+			return nil, errors.Errorf("%s is required when other ssh parameters are set", QueryHost)
+		}
+
+		return nil, nil
+	}
+
+	if port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil || p <= 0 || p > 65535 {
+			return nil, errors.Errorf("invalid %s %q", QueryPort, port)
+		}
+		conf.Port = p
+	}
+
+	switch {
+	case conf.Password != "":
+		conf.Type = ConfigTypeByPassword
+	case conf.PrivateKey != "":
+		conf.Type = ConfigTypeByPrivateKey
+	case conf.PrivateKeyPath != "":
+		conf.Type = ConfigTypeByPrivateKeyPath
+	default:
+		return nil, errors.Errorf("one of %s, %s or %s is required", QueryPassword, QueryKey, QueryPrivateKey)
+	}
+
+	return conf, nil
+}
+
+// EncodeQuery 将 SSH 配置写入 URL 查询参数，与 ConfigFromQuery 互为逆操作。
+// 只写入当前认证方式使用的参数。
+//
+// 参数:
+//   - query: 要写入的查询参数。
+//   - redact: 是否将密码与私钥内容替换为 RedactedSecret，私钥文件路径不是敏感信息，不会被替换。
+func (c Config) EncodeQuery(query url.Values, redact bool) {
+	query.Set(QueryHost, c.Host)
+	if c.Port != 0 && c.Port != 22 {
+		query.Set(QueryPort, strconv.Itoa(c.Port))
+	}
+	if c.User != "" {
+		query.Set(QueryUser, c.User)
+	}
+
+	secret := func(s string) string {
+		if redact && s != "" {
+			return RedactedSecret
+		}
+		return s
+	}
+
+	switch c.Type {
+	case ConfigTypeByPassword:
+		query.Set(QueryPassword, secret(c.Password))
+	case ConfigTypeByPrivateKey:
+		query.Set(QueryPrivateKey, secret(c.PrivateKey))
+	case ConfigTypeByPrivateKeyPath:
+		query.Set(QueryKey, c.PrivateKeyPath)
+	}
+}